@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	stdlog "log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/accrualsim"
+	"github.com/Soliard/gophermart/internal/logger"
+)
+
+func main() {
+	err := logger.Init()
+	if err != nil {
+		stdlog.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	config, err := accrualsim.NewConfig()
+	if err != nil {
+		logger.Log.Fatal("Failed to create config", logger.F.Error(err))
+	}
+
+	logger.Log.Info("Used config", logger.F.Any("config", config))
+
+	rules, err := config.Rules()
+	if err != nil {
+		logger.Log.Fatal("Failed to load rules", logger.F.Error(err))
+	}
+
+	ctx, ctxStop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer ctxStop()
+
+	server := &http.Server{
+		Addr:              config.ServerHost,
+		Handler:           accrualsim.NewRouter(accrualsim.NewSimulator(rules, config.StepPolls), config.Faults()),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		logger.Log.Info("Starting accrual simulator...")
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info("shutdown signal recieved")
+	case err := <-errCh:
+		if err != nil {
+			logger.Log.Fatal("server failed", logger.F.Error(err))
+		}
+	}
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = server.Shutdown(ctxShutdown)
+	if err != nil {
+		logger.Log.Error("graceful server shutdown failed", logger.F.Error(err))
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Soliard/gophermart/internal/app"
 	"github.com/Soliard/gophermart/internal/config"
 	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/tracing"
 )
 
 func main() {
@@ -33,6 +34,19 @@ func main() {
 	ctx, ctxStop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	defer ctxStop()
 
+	shutdownTracing, err := tracing.Init(ctx, config.TracingExporter, config.TracingEndpoint)
+	if err != nil {
+		logger.Log.Fatal("Failed to initialize tracing", logger.F.Error(err))
+	}
+	defer func() {
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := shutdownTracing(ctxShutdown)
+		if err != nil {
+			logger.Log.Error("Failed to flush traces", logger.F.Error(err))
+		}
+	}()
+
 	app, err := app.New(ctx, config)
 	if err != nil {
 		logger.Log.Fatal("Failed to create app", logger.F.Error(err))
@@ -70,6 +84,11 @@ func main() {
 		ctxStop()
 	}
 
+	// Fail readiness first and keep serving for a while, so that the load
+	// balancer stops sending new requests before the listener is closed.
+	app.Services.Health.Shutdown()
+	time.Sleep(time.Duration(app.Config.ShutdownDelaySeconds) * time.Second)
+
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	err = server.Shutdown(ctxShutdown)
@@ -0,0 +1,74 @@
+package accrualsim
+
+import (
+	"flag"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/caarlos0/env/v6"
+)
+
+type Config struct {
+	ServerHost string `env:"RUN_ADDRESS"`
+	RulesFile  string `env:"ACCRUAL_SIM_RULES"`
+	Reward     string `env:"ACCRUAL_SIM_REWARD"`
+	StepPolls  int    `env:"ACCRUAL_SIM_STEP_POLLS"`
+
+	RateLimit         int     `env:"ACCRUAL_SIM_RATE_LIMIT"`
+	RetryAfterSeconds int     `env:"ACCRUAL_SIM_RETRY_AFTER"`
+	LatencyMillis     int     `env:"ACCRUAL_SIM_LATENCY"`
+	ErrorRate         float64 `env:"ACCRUAL_SIM_ERROR_RATE"`
+}
+
+func NewConfig() (*Config, error) {
+	config := &Config{}
+
+	flag.StringVar(&config.ServerHost, "a", "localhost:5050", "server addres")
+	flag.StringVar(&config.RulesFile, "rules", "", "json file with reward rules, checked before the default reward")
+	flag.StringVar(&config.Reward, "reward", "500", "accrual of processed orders that match no rule")
+	flag.IntVar(&config.StepPolls, "step-polls", 1,
+		"number of requests an order stays REGISTERED and then PROCESSING before the final status")
+	flag.IntVar(&config.RateLimit, "rate-limit", 0, "requests per minute before answering 429, 0 disables the limit")
+	flag.IntVar(&config.RetryAfterSeconds, "retry-after", 60, "Retry-After value in seconds of 429 responses")
+	flag.IntVar(&config.LatencyMillis, "latency", 0, "delay in milliseconds before every response")
+	flag.Float64Var(&config.ErrorRate, "error-rate", 0, "share of requests from 0 to 1 answered with 500")
+	flag.Parse()
+
+	err := env.Parse(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Rules returns the rules from the rules file followed by the default rule
+// that processes every other order with the default reward.
+func (c *Config) Rules() ([]Rule, error) {
+	var rules []Rule
+	if c.RulesFile != "" {
+		var err error
+		rules, err = LoadRules(c.RulesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reward, err := models.ParsePoints(c.Reward)
+	if err != nil {
+		return nil, err
+	}
+	fallback := Rule{Status: string(models.StatusProcessed), Accrual: reward}
+	err = fallback.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, fallback), nil
+}
+
+func (c *Config) Faults() *Faults {
+	return NewFaults(c.RateLimit,
+		time.Duration(c.RetryAfterSeconds)*time.Second,
+		time.Duration(c.LatencyMillis)*time.Millisecond,
+		c.ErrorRate)
+}
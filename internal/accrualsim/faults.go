@@ -0,0 +1,81 @@
+package accrualsim
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Faults injects the failures the real accrual system is known for: rate
+// limiting with Retry-After, slow responses and internal errors.
+type Faults struct {
+	rateLimit  int
+	retryAfter time.Duration
+	latency    time.Duration
+	errorRate  float64
+	random     func() float64
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+}
+
+// NewFaults limits the requests per minute unless rateLimit is 0, delays
+// every response by latency and fails the errorRate share of requests with
+// 500 Internal Server Error.
+func NewFaults(rateLimit int, retryAfter, latency time.Duration, errorRate float64) *Faults {
+	return &Faults{
+		rateLimit:  rateLimit,
+		retryAfter: retryAfter,
+		latency:    latency,
+		errorRate:  errorRate,
+		random:     rand.Float64,
+	}
+}
+
+func (f *Faults) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.latency > 0 {
+			select {
+			case <-time.After(f.latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if !f.allow(time.Now()) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.retryAfter.Seconds())))
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, "No more than %d requests per minute allowed", f.rateLimit)
+			return
+		}
+
+		if f.errorRate > 0 && f.random() < f.errorRate {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow counts the request in a fixed one minute window.
+func (f *Faults) allow(now time.Time) bool {
+	if f.rateLimit <= 0 {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Sub(f.windowStart) >= time.Minute {
+		f.windowStart = now
+		f.requests = 0
+	}
+	f.requests++
+	return f.requests <= f.rateLimit
+}
@@ -0,0 +1,77 @@
+package accrualsim
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestFaults_RateLimit(t *testing.T) {
+	faults := NewFaults(2, 30*time.Second, 0, 0)
+	handler := faults.Middleware(http.HandlerFunc(okHandler))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	require.Equal(t, "No more than 2 requests per minute allowed", string(body))
+}
+
+func TestFaults_RateLimitWindow(t *testing.T) {
+	faults := NewFaults(1, time.Second, 0, 0)
+	start := time.Now()
+
+	require.True(t, faults.allow(start))
+	require.False(t, faults.allow(start.Add(30*time.Second)))
+	require.True(t, faults.allow(start.Add(time.Minute)))
+}
+
+func TestFaults_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		random   float64
+		expected int
+	}{
+		{name: "запрос попал в долю ошибок", random: 0.1, expected: http.StatusInternalServerError},
+		{name: "запрос обработан", random: 0.9, expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := NewFaults(0, 0, 0, 0.5)
+			faults.random = func() float64 { return tt.random }
+
+			w := httptest.NewRecorder()
+			faults.Middleware(http.HandlerFunc(okHandler)).
+				ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+			require.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestFaults_Latency(t *testing.T) {
+	faults := NewFaults(0, 0, 50*time.Millisecond, 0)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	faults.Middleware(http.HandlerFunc(okHandler)).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,16 @@
+package accrualsim
+
+import (
+	"github.com/Soliard/gophermart/internal/middlewares"
+	"github.com/go-chi/chi"
+)
+
+func NewRouter(sim *Simulator, faults *Faults) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middlewares.Logging)
+	r.Use(faults.Middleware)
+
+	r.Get("/api/orders/{number}", sim.GetOrder)
+
+	return r
+}
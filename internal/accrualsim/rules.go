@@ -0,0 +1,62 @@
+package accrualsim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+// StatusUnregistered is a rule outcome for orders the accrual system does not
+// know, they are answered with 204 No Content.
+const StatusUnregistered = "UNREGISTERED"
+
+// Rule decides the final outcome of orders whose number starts with Prefix.
+// An empty prefix matches every order.
+type Rule struct {
+	Prefix  string        `json:"prefix"`
+	Status  string        `json:"status"`
+	Accrual models.Points `json:"accrual"`
+}
+
+func (r Rule) Validate() error {
+	switch models.OrderStatus(r.Status) {
+	case models.StatusProcessed, models.StatusInvalid, StatusUnregistered:
+	default:
+		return fmt.Errorf("%w: status %q, expected PROCESSED, INVALID or UNREGISTERED",
+			errs.ErrAccrualSimRuleInvalid, r.Status)
+	}
+	if r.Accrual < 0 {
+		return fmt.Errorf("%w: negative accrual %s", errs.ErrAccrualSimRuleInvalid, r.Accrual)
+	}
+	return nil
+}
+
+func (r Rule) Matches(number string) bool {
+	return strings.HasPrefix(number, r.Prefix)
+}
+
+// LoadRules reads a JSON array of rules, for example
+//
+//	[{"prefix": "9", "status": "INVALID"}, {"prefix": "", "status": "PROCESSED", "accrual": 729.98}]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrAccrualSimRuleInvalid, err)
+	}
+	for _, r := range rules {
+		err = r.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,85 @@
+// Package accrualsim imitates the external accrual system for local
+// development and tests.
+package accrualsim
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/go-chi/chi"
+)
+
+const (
+	statusRegistered = "REGISTERED"
+	statusProcessing = "PROCESSING"
+)
+
+// Simulator answers order requests like the accrual system. Every order moves
+// through REGISTERED and PROCESSING, stepPolls requests in each, before the
+// final status of the first matching rule is reported.
+type Simulator struct {
+	rules     []Rule
+	stepPolls int
+
+	mu    sync.Mutex
+	polls map[string]int
+}
+
+func NewSimulator(rules []Rule, stepPolls int) *Simulator {
+	return &Simulator{
+		rules:     rules,
+		stepPolls: max(stepPolls, 0),
+		polls:     make(map[string]int),
+	}
+}
+
+// Order returns the current state of the order and counts the request. It
+// reports false for orders that are unknown to the accrual system.
+func (s *Simulator) Order(number string) (*dto.AccrualOrder, bool) {
+	rule, ok := s.rule(number)
+	if !ok || rule.Status == StatusUnregistered {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.polls[number]++
+	polls := s.polls[number]
+	s.mu.Unlock()
+
+	order := &dto.AccrualOrder{Order: number}
+	switch {
+	case polls <= s.stepPolls:
+		order.Status = statusRegistered
+	case polls <= 2*s.stepPolls:
+		order.Status = statusProcessing
+	default:
+		order.Status = rule.Status
+		if models.OrderStatus(rule.Status) == models.StatusProcessed {
+			accrual := rule.Accrual
+			order.Accrual = &accrual
+		}
+	}
+	return order, true
+}
+
+func (s *Simulator) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := s.Order(chi.URLParam(r, "number"))
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (s *Simulator) rule(number string) (Rule, bool) {
+	for _, r := range s.rules {
+		if r.Matches(number) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
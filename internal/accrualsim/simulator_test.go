@@ -0,0 +1,176 @@
+package accrualsim_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/accrualsim"
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func testRules() []accrualsim.Rule {
+	return []accrualsim.Rule{
+		{Prefix: "9", Status: string(models.StatusInvalid)},
+		{Prefix: "8", Status: accrualsim.StatusUnregistered},
+		{Prefix: "", Status: string(models.StatusProcessed), Accrual: 72998},
+	}
+}
+
+func TestSimulator_Order(t *testing.T) {
+	accrual := models.Points(72998)
+	tests := []struct {
+		name      string
+		number    string
+		stepPolls int
+		expected  []dto.AccrualOrder
+	}{
+		{
+			name:      "статусы по очереди до начисления",
+			number:    "12345678903",
+			stepPolls: 1,
+			expected: []dto.AccrualOrder{
+				{Order: "12345678903", Status: "REGISTERED"},
+				{Order: "12345678903", Status: "PROCESSING"},
+				{Order: "12345678903", Status: "PROCESSED", Accrual: &accrual},
+				{Order: "12345678903", Status: "PROCESSED", Accrual: &accrual},
+			},
+		},
+		{
+			name:      "каждый промежуточный статус повторяется",
+			number:    "12345678903",
+			stepPolls: 2,
+			expected: []dto.AccrualOrder{
+				{Order: "12345678903", Status: "REGISTERED"},
+				{Order: "12345678903", Status: "REGISTERED"},
+				{Order: "12345678903", Status: "PROCESSING"},
+				{Order: "12345678903", Status: "PROCESSING"},
+				{Order: "12345678903", Status: "PROCESSED", Accrual: &accrual},
+			},
+		},
+		{
+			name:      "финальный статус сразу",
+			number:    "9278923470",
+			stepPolls: 0,
+			expected: []dto.AccrualOrder{
+				{Order: "9278923470", Status: "INVALID"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := accrualsim.NewSimulator(testRules(), tt.stepPolls)
+			for _, expected := range tt.expected {
+				order, ok := sim.Order(tt.number)
+				require.True(t, ok)
+				require.Equal(t, expected, *order)
+			}
+		})
+	}
+}
+
+func TestSimulator_GetOrder(t *testing.T) {
+	server := httptest.NewServer(accrualsim.NewRouter(
+		accrualsim.NewSimulator(testRules(), 0), accrualsim.NewFaults(0, 0, 0, 0)))
+	defer server.Close()
+
+	t.Run("незарегистрированный заказ", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/orders/8888")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("начисление по правилу", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/orders/12345678903")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var order dto.AccrualOrder
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+		require.Equal(t, "PROCESSED", order.Status)
+		require.Equal(t, models.Points(72998), *order.Accrual)
+	})
+}
+
+// TestSimulator_AccrualService runs the accrual polling against the simulator
+// and the in-memory storage, without any mocks.
+func TestSimulator_AccrualService(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(accrualsim.NewRouter(
+		accrualsim.NewSimulator(testRules(), 1), accrualsim.NewFaults(0, 0, 0, 0)))
+	defer server.Close()
+
+	s := memory.New()
+	users := memory.NewUserRepository(s)
+	orders := memory.NewOrderRepository(s)
+	balance := memory.NewBalanceRepository(s)
+
+	u := models.NewUser("alice", "hash")
+	require.NoError(t, users.Create(ctx, u))
+	require.NoError(t, orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+	require.NoError(t, orders.Create(ctx, models.NewOrder("9278923470", u.ID)))
+
+	service := services.NewAccrualService(orders, server.URL, services.AccrualConfig{
+		Workers:   2,
+		BatchSize: 10,
+		Timeout:   time.Second,
+		Retry:     services.RetryConfig{MaxRetries: 1},
+	})
+	expected := []struct {
+		processed models.OrderStatus
+		invalid   models.OrderStatus
+	}{
+		{processed: models.StatusProcessing, invalid: models.StatusProcessing},
+		{processed: models.StatusProcessing, invalid: models.StatusProcessing},
+		{processed: models.StatusProcessed, invalid: models.StatusInvalid},
+	}
+	for _, e := range expected {
+		requeue(t, orders, "12345678903", "9278923470")
+		require.NoError(t, service.UpdateOrders(ctx))
+		requireStatus(t, orders, "12345678903", e.processed)
+		requireStatus(t, orders, "9278923470", e.invalid)
+	}
+
+	b, err := balance.GetUserBalance(ctx, u.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.Points(72998), b.Current)
+}
+
+// requeue releases the lease of the previous poll, so the next poll sees the orders again.
+func requeue(t *testing.T, orders *memory.OrderRepository, numbers ...string) {
+	t.Helper()
+	for _, number := range numbers {
+		order, err := orders.GetByNumber(context.Background(), number)
+		require.NoError(t, err)
+		if !order.Status.IsFinal() {
+			require.NoError(t, orders.ScheduleAccrualRetry(context.Background(), number, -time.Second))
+		}
+	}
+}
+
+func requireStatus(t *testing.T, orders *memory.OrderRepository, number string, status models.OrderStatus) {
+	t.Helper()
+	order, err := orders.GetByNumber(context.Background(), number)
+	require.NoError(t, err)
+	require.Equal(t, status, order.Status)
+}
@@ -2,74 +2,143 @@ package app
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/Soliard/gophermart/internal/config"
 	"github.com/Soliard/gophermart/internal/handlers"
+	"github.com/Soliard/gophermart/internal/metrics"
 	"github.com/Soliard/gophermart/internal/middlewares"
 	"github.com/Soliard/gophermart/internal/models"
 	"github.com/Soliard/gophermart/internal/services"
-	"github.com/Soliard/gophermart/internal/storage/postgr"
 	"github.com/Soliard/gophermart/internal/workers"
 	"github.com/go-chi/chi"
-	"github.com/jmoiron/sqlx"
 )
 
 type App struct {
 	Config   *config.Config
 	Handlers *handlers.Handlers
 	Services *services.Services
-	db       *sqlx.DB
+	storage  *storage
+	metrics  http.Handler
 }
 
 func New(ctx context.Context, cfg *config.Config) (*App, error) {
-	db, err := postgr.NewConnection(ctx, cfg.DatabaseDSN)
+	err := cfg.Validate()
 	if err != nil {
 		return nil, err
 	}
-	repoUser := postgr.NewUserRepository(db)
-	repoOrder := postgr.NewOrderRepository(db)
-	repoWithdrawal := postgr.NewWithdrawalRepository(db)
-	repoBalance := postgr.NewBalanceRepository(db)
 
-	services := services.New(repoUser, repoOrder, repoWithdrawal, repoBalance, cfg)
-	handlers := handlers.New(services)
+	storage, err := newStorage(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	accrualUpdater := workers.NewAccrualUpdater(services.Accrual, time.Duration(time.Second*10))
+	services, err := services.New(storage.repos, cfg)
+	if err != nil {
+		storage.close()
+		return nil, err
+	}
+
+	accrualUpdater := workers.NewAccrualUpdater(services.Accrual,
+		time.Duration(cfg.AccrualPollIntervalSeconds)*time.Second)
 	go accrualUpdater.Start(ctx)
 
+	services.Health = newHealthService(storage.checks, services.Accrual, accrualUpdater)
+	handlers := handlers.New(services)
+
+	balanceReconciler := workers.NewBalanceReconciler(services.Balance, time.Duration(time.Hour))
+	go balanceReconciler.Start(ctx)
+
+	idempotencyCleaner := workers.NewIdempotencyCleaner(services.Idempotency, time.Duration(time.Hour))
+	go idempotencyCleaner.Start(ctx)
+
+	loginAttemptsCleaner := workers.NewLoginAttemptsCleaner(services.LoginGuard, time.Duration(time.Hour))
+	go loginAttemptsCleaner.Start(ctx)
+
 	return &App{
 		Config:   cfg,
 		Handlers: handlers,
 		Services: services,
-		db:       db,
+		storage:  storage,
+		metrics: metrics.Handler(
+			append(storage.collectors, metrics.NewOrderQueueCollector(storage.repos.Orders))...,
+		),
 	}, nil
 }
 
+func newHealthService(
+	storageChecks []services.HealthCheck, accrual services.AccrualServiceInterface, updater interface {
+		CheckFreshness(ctx context.Context) error
+	}) services.HealthServiceInterface {
+
+	checks := append(storageChecks,
+		services.HealthCheck{Name: "accrual", Check: accrual.Ping},
+		services.HealthCheck{Name: "accrual_poll", Check: updater.CheckFreshness},
+	)
+	return services.NewHealthService(checks...)
+}
+
 func (a *App) Router() *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(middlewares.Tracing)
 	r.Use(middlewares.Logging)
+	r.Use(middlewares.Metrics)
+
+	r.Handle("/metrics", a.metrics)
+	r.Get("/healthz", a.Handlers.Health.Liveness)
+	r.Get("/readyz", a.Handlers.Health.Readiness)
+
+	r.Get("/.well-known/jwks.json", a.Handlers.JWKS.GetJWKS)
 
 	r.Post("/api/user/register", a.Handlers.User.Register)
 	r.Post("/api/user/login", a.Handlers.User.Login)
+	r.Post("/api/user/login/2fa", a.Handlers.User.LoginTwoFactor)
+	r.Post("/api/user/token/refresh", a.Handlers.User.RefreshToken)
 
 	r.Group(func(r chi.Router) {
-		r.Use(middlewares.Authentication(a.Services.JWT))
+		r.Use(middlewares.Authentication(a.Services.JWT, a.Services.Session))
+
+		r.Post("/api/user/password", a.Handlers.User.ChangePassword)
+		r.Post("/api/user/logout", a.Handlers.User.Logout)
+		r.Post("/api/user/logout/all", a.Handlers.User.LogoutAll)
+		r.Post("/api/user/2fa/enroll", a.Handlers.TwoFactor.Enroll)
+		r.Post("/api/user/2fa/confirm", a.Handlers.TwoFactor.Confirm)
 
 		r.Group(func(r chi.Router) {
 			r.Use(middlewares.Authorization(models.RoleUser))
-			r.Post("/api/user/orders", a.Handlers.Order.UploadOrder)
+			r.With(middlewares.Idempotency(a.Services.Idempotency)).
+				Post("/api/user/orders", a.Handlers.Order.UploadOrder)
 			r.Get("/api/user/orders", a.Handlers.Order.GetUserOrders)
 			r.Get("/api/user/balance", a.Handlers.Balance.GetBalance)
-			r.Post("/api/user/balance/withdraw", a.Handlers.Withdrawal.ProcessWithdrawal)
+			r.Get("/api/user/balance/history", a.Handlers.Balance.GetHistory)
+			r.With(middlewares.Idempotency(a.Services.Idempotency)).
+				Post("/api/user/balance/withdraw", a.Handlers.Withdrawal.ProcessWithdrawal)
 			r.Get("/api/user/withdrawals", a.Handlers.Withdrawal.GetWithdrawals)
 		})
 
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(middlewares.Authorization(models.RoleAdmin))
+			r.Get("/users", a.Handlers.Admin.ListUsers)
+			r.Get("/users/{userID}/orders", a.Handlers.Admin.GetUserOrders)
+			r.Get("/users/{userID}/withdrawals", a.Handlers.Admin.GetUserWithdrawals)
+			r.Post("/users/{userID}/roles", a.Handlers.Admin.GrantRole)
+			r.Delete("/users/{userID}/roles/{role}", a.Handlers.Admin.RevokeRole)
+			r.Post("/users/{userID}/block", a.Handlers.Admin.BlockUser)
+			r.Delete("/users/{userID}/block", a.Handlers.Admin.UnblockUser)
+			r.Delete("/users/{userID}/lockout", a.Handlers.Admin.UnlockUser)
+			r.Get("/users/{userID}/balance/adjustments", a.Handlers.Admin.GetBalanceAdjustments)
+			r.With(middlewares.Idempotency(a.Services.Idempotency)).
+				Post("/users/{userID}/balance/adjustments", a.Handlers.Admin.AdjustBalance)
+			r.Post("/orders/{number}/requeue", a.Handlers.Admin.RequeueOrder)
+			r.Get("/audit", a.Handlers.Admin.GetAuditLog)
+		})
+
 	})
 
 	return r
 }
 
 func (a *App) Close() error {
-	return a.db.Close()
+	return a.storage.close()
 }
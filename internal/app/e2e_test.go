@@ -0,0 +1,377 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/accrualsim"
+	"github.com/Soliard/gophermart/internal/config"
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/postgr"
+	"github.com/Soliard/gophermart/internal/storage/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+const testPassword = "Secr3tPass!"
+
+// testServer is the application router behind a real HTTP server, with the
+// accrual system replaced by the simulator.
+type testServer struct {
+	t   *testing.T
+	app *App
+	url string
+}
+
+// newTestServer builds the app over the storage selected by configure. The
+// simulator reports REGISTERED and PROCESSING once each and then processes
+// every order with 729.98 points, orders starting with 9 are invalid.
+func newTestServer(t *testing.T, configure func(cfg *config.Config)) *testServer {
+	t.Helper()
+
+	sim := httptest.NewServer(accrualsim.NewRouter(
+		accrualsim.NewSimulator([]accrualsim.Rule{
+			{Prefix: "9", Status: string(models.StatusInvalid)},
+			{Prefix: "", Status: string(models.StatusProcessed), Accrual: 72998},
+		}, 1),
+		accrualsim.NewFaults(0, 0, 0, 0),
+	))
+	t.Cleanup(sim.Close)
+
+	cfg := &config.Config{
+		StorageBackend:  StorageBackendMemory,
+		TokenSecret:     "test-secret",
+		TokenExpMinutes: 10,
+		RefreshExpHours: 1,
+		AccrualAddress:  sim.URL,
+		AccrualWorkers:  1,
+
+		AccrualPollIntervalSeconds: 10,
+		AccrualBatchSize:           100,
+		AccrualTimeoutSeconds:      5,
+		AccrualMaxRetries:          3,
+		AccrualRetryDelaySeconds:   1,
+
+		IdempotencyTTLHours: 1,
+		LoginMaxAttempts:    5,
+		LoginIPMaxAttempts:  50,
+		LoginLockoutMinutes: 15,
+		PasswordMinLength:   8,
+		PasswordMinClasses:  2,
+		PasswordHash:        "bcrypt",
+		BcryptCost:          4,
+		Argon2MemoryKiB:     1024,
+		Argon2Iterations:    1,
+		Argon2Parallelism:   1,
+	}
+	configure(cfg)
+
+	app, err := New(t.Context(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { app.Close() })
+
+	server := httptest.NewServer(app.Router())
+	t.Cleanup(server.Close)
+
+	return &testServer{t: t, app: app, url: server.URL}
+}
+
+func (s *testServer) do(method, path, token, contentType, body string) (int, http.Header, string) {
+	s.t.Helper()
+
+	req, err := http.NewRequest(method, s.url+path, strings.NewReader(body))
+	require.NoError(s.t, err)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(s.t, err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(s.t, err)
+	return resp.StatusCode, resp.Header, string(respBody)
+}
+
+// register creates a user and returns the access token.
+func (s *testServer) register(login string) string {
+	s.t.Helper()
+	status, header, _ := s.do(http.MethodPost, "/api/user/register", "", "application/json",
+		`{"login":"`+login+`","password":"`+testPassword+`"}`)
+	require.Equal(s.t, http.StatusOK, status)
+	require.NotEmpty(s.t, header.Get("Authorization"))
+	return header.Get("Authorization")
+}
+
+// pollAccrual runs the accrual polls without waiting for the background worker.
+func (s *testServer) pollAccrual(times int) {
+	s.t.Helper()
+	for i := 0; i < times; i++ {
+		require.NoError(s.t, s.app.Services.Accrual.UpdateOrders(context.Background()))
+	}
+}
+
+func decode[T any](t *testing.T, body string) T {
+	t.Helper()
+	var v T
+	require.NoError(t, json.Unmarshal([]byte(body), &v))
+	return v
+}
+
+func TestE2E(t *testing.T) {
+	backends := map[string]func(t *testing.T) func(cfg *config.Config){
+		"memory": func(t *testing.T) func(cfg *config.Config) {
+			return func(cfg *config.Config) { cfg.StorageBackend = StorageBackendMemory }
+		},
+		"sqlite": func(t *testing.T) func(cfg *config.Config) {
+			dsn := sqlite.Scheme + filepath.Join(t.TempDir(), "gophermart.db")
+			return func(cfg *config.Config) {
+				cfg.StorageBackend = StorageBackendDatabase
+				cfg.DatabaseDSN = dsn
+			}
+		},
+		"postgres": func(t *testing.T) func(cfg *config.Config) {
+			dsn := os.Getenv("TEST_DATABASE_URI")
+			if dsn == "" {
+				t.Skip("TEST_DATABASE_URI is not set")
+			}
+			truncatePostgres(t, dsn)
+			return func(cfg *config.Config) {
+				cfg.StorageBackend = StorageBackendDatabase
+				cfg.DatabaseDSN = dsn
+			}
+		},
+	}
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			configure := backend(t)
+			t.Run("Journey", func(t *testing.T) { testJourney(t, newTestServer(t, configure)) })
+		})
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(t.Context(), &config.Config{StorageBackend: StorageBackendMemory})
+	require.ErrorIs(t, err, errs.ErrConfigInvalid)
+}
+
+func truncatePostgres(t *testing.T, dsn string) {
+	t.Helper()
+	db, err := postgr.NewConnection(context.Background(), dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`TRUNCATE users, orders, withdrawals, balances, ledger_entries,
+		idempotency_keys, sessions, audit_log, balance_adjustments, login_attempts, recovery_codes CASCADE`)
+	require.NoError(t, err)
+}
+
+// testJourney walks a user from registration to a withdrawal and checks the
+// documented status codes on the way.
+func testJourney(t *testing.T, s *testServer) {
+	const (
+		processedOrder = "12345678903"
+		invalidOrder   = "9278923470"
+		withdrawOrder  = "2377225624"
+	)
+
+	t.Run("регистрация", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodPost, "/api/user/register", "", "text/plain",
+			`{"login":"alice","password":"`+testPassword+`"}`)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/register", "", "application/json",
+			`{"login":"alice","password":""}`)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		s.register("alice")
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/register", "", "application/json",
+			`{"login":"alice","password":"`+testPassword+`"}`)
+		require.Equal(t, http.StatusConflict, status)
+	})
+
+	var token string
+	t.Run("вход", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodPost, "/api/user/login", "", "application/json",
+			`{"login":"alice","password":"wrong-Passw0rd"}`)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/login", "", "application/json",
+			`{"login":"`+strings.Repeat("a", 300)+`","password":"wrong-Passw0rd"}`)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, header, _ := s.do(http.MethodPost, "/api/user/login", "", "application/json",
+			`{"login":"alice","password":"`+testPassword+`"}`)
+		require.Equal(t, http.StatusOK, status)
+		token = header.Get("Authorization")
+		require.NotEmpty(t, token)
+	})
+	require.NotEmpty(t, token)
+
+	t.Run("без авторизации", func(t *testing.T) {
+		for _, path := range []string{"/api/user/orders", "/api/user/balance", "/api/user/withdrawals"} {
+			status, _, _ := s.do(http.MethodGet, path, "", "", "")
+			require.Equal(t, http.StatusUnauthorized, status, path)
+		}
+		status, _, _ := s.do(http.MethodPost, "/api/user/orders", "", "text/plain", processedOrder)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("пустые списки", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodGet, "/api/user/orders", token, "", "")
+		require.Equal(t, http.StatusNoContent, status)
+		status, _, _ = s.do(http.MethodGet, "/api/user/withdrawals", token, "", "")
+		require.Equal(t, http.StatusNoContent, status)
+	})
+
+	t.Run("загрузка заказов", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodPost, "/api/user/orders", token, "application/json", processedOrder)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/orders", token, "text/plain", "12345678900")
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/orders", token, "text/plain", processedOrder)
+		require.Equal(t, http.StatusAccepted, status)
+		status, _, _ = s.do(http.MethodPost, "/api/user/orders", token, "text/plain", invalidOrder)
+		require.Equal(t, http.StatusAccepted, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/orders", token, "text/plain", processedOrder)
+		require.Equal(t, http.StatusOK, status)
+
+		bob := s.register("bob")
+		status, _, _ = s.do(http.MethodPost, "/api/user/orders", bob, "text/plain", processedOrder)
+		require.Equal(t, http.StatusConflict, status)
+
+		status, _, body := s.do(http.MethodGet, "/api/user/orders", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		orders := decode[[]models.Order](t, body)
+		require.Len(t, orders, 2)
+		for _, o := range orders {
+			require.Equal(t, models.StatusNew, o.Status)
+		}
+	})
+
+	t.Run("расчёт начислений", func(t *testing.T) {
+		s.pollAccrual(1)
+		status, _, body := s.do(http.MethodGet, "/api/user/orders", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		for _, o := range decode[[]models.Order](t, body) {
+			require.Equal(t, models.StatusProcessing, o.Status)
+			require.Nil(t, o.Accrual)
+		}
+
+		s.pollAccrual(2)
+		status, _, body = s.do(http.MethodGet, "/api/user/orders", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		statuses := map[string]models.Order{}
+		for _, o := range decode[[]models.Order](t, body) {
+			statuses[o.Number] = o
+		}
+		require.Equal(t, models.StatusProcessed, statuses[processedOrder].Status)
+		require.Equal(t, models.Points(72998), *statuses[processedOrder].Accrual)
+		require.Equal(t, models.StatusInvalid, statuses[invalidOrder].Status)
+		require.Nil(t, statuses[invalidOrder].Accrual)
+
+		status, _, body = s.do(http.MethodGet, "/api/user/balance", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		balance := decode[models.Balance](t, body)
+		require.Equal(t, models.Points(72998), balance.Current)
+		require.Zero(t, balance.Withdrawn)
+	})
+
+	t.Run("списание", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodPost, "/api/user/balance/withdraw", token, "application/json",
+			`{"order":"12345678900","sum":100}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/balance/withdraw", token, "application/json",
+			`{"order":"`+withdrawOrder+`","sum":0}`)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/balance/withdraw", token, "application/json",
+			`{"order":"`+withdrawOrder+`","sum":1000}`)
+		require.Equal(t, http.StatusPaymentRequired, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/balance/withdraw", token, "application/json",
+			`{"order":"`+withdrawOrder+`","sum":100.5}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _, _ = s.do(http.MethodPost, "/api/user/balance/withdraw", token, "application/json",
+			`{"order":"`+withdrawOrder+`","sum":100.5}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _, body := s.do(http.MethodGet, "/api/user/balance", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		balance := decode[models.Balance](t, body)
+		require.Equal(t, models.Points(62948), balance.Current)
+		require.Equal(t, models.Points(10050), balance.Withdrawn)
+
+		status, _, body = s.do(http.MethodGet, "/api/user/withdrawals", token, "", "")
+		require.Equal(t, http.StatusOK, status)
+		withdrawals := decode[[]models.Withdrawal](t, body)
+		require.Len(t, withdrawals, 1)
+		require.Equal(t, withdrawOrder, withdrawals[0].OrderNumber)
+		require.Equal(t, models.Points(10050), withdrawals[0].Sum)
+	})
+
+	t.Run("администрирование", func(t *testing.T) {
+		s.register("root")
+		root, err := s.app.storage.repos.Users.GetByLogin(context.Background(), "root")
+		require.NoError(t, err)
+		entry := models.NewAuditEntry(root.ID, models.AuditRoleGranted, models.AuditTargetUser, root.ID, nil)
+		err = s.app.storage.repos.Admin.UpdateRoles(context.Background(), root.ID,
+			models.Roles{models.RoleUser, models.RoleAdmin}, entry)
+		require.NoError(t, err)
+
+		status, header, _ := s.do(http.MethodPost, "/api/user/login", "", "application/json",
+			`{"login":"root","password":"`+testPassword+`"}`)
+		require.Equal(t, http.StatusOK, status)
+		admin := header.Get("Authorization")
+
+		status, _, _ = s.do(http.MethodGet, "/api/admin/users/not-a-uuid/orders", admin, "", "")
+		require.Equal(t, http.StatusBadRequest, status)
+		status, _, _ = s.do(http.MethodPost, "/api/admin/users/not-a-uuid/block", admin, "", "")
+		require.Equal(t, http.StatusBadRequest, status)
+
+		nobody := models.NewUser("nobody", "hash").ID
+		status, _, _ = s.do(http.MethodGet, "/api/admin/users/"+nobody+"/orders", admin, "", "")
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("служебные эндпоинты", func(t *testing.T) {
+		status, _, _ := s.do(http.MethodGet, "/healthz", "", "", "")
+		require.Equal(t, http.StatusOK, status)
+
+		status, _, body := s.do(http.MethodGet, "/readyz", "", "", "")
+		require.Equal(t, http.StatusOK, status)
+		var ready dto.HealthResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &ready))
+		require.Equal(t, services.HealthStatusOK, ready.Checks["accrual"].Status)
+
+		status, _, body = s.do(http.MethodGet, "/metrics", "", "", "")
+		require.Equal(t, http.StatusOK, status)
+		require.Contains(t, body, `route="/api/user/balance/withdraw"`)
+	})
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Soliard/gophermart/internal/config"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/memory"
+	"github.com/Soliard/gophermart/internal/storage/postgr"
+	"github.com/Soliard/gophermart/internal/storage/sqlite"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	StorageBackendDatabase = "database"
+	StorageBackendMemory   = "memory"
+)
+
+// storage is a repository set together with what the backend adds to
+// readiness and metrics.
+type storage struct {
+	repos      *services.Repositories
+	checks     []services.HealthCheck
+	collectors []prometheus.Collector
+	close      func() error
+}
+
+func newStorage(ctx context.Context, cfg *config.Config) (*storage, error) {
+	switch cfg.StorageBackend {
+	case StorageBackendDatabase:
+		if strings.HasPrefix(cfg.DatabaseDSN, sqlite.Scheme) {
+			return newSQLiteStorage(ctx, cfg.DatabaseDSN)
+		}
+		return newPostgresStorage(ctx, cfg.DatabaseDSN)
+	case StorageBackendMemory:
+		return newMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errs.ErrStorageBackendUnknown, cfg.StorageBackend)
+	}
+}
+
+func newPostgresStorage(ctx context.Context, dsn string) (*storage, error) {
+	db, err := postgr.NewConnection(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &storage{
+		repos: &services.Repositories{
+			Users:         postgr.NewUserRepository(db),
+			Orders:        postgr.NewOrderRepository(db),
+			Withdrawals:   postgr.NewWithdrawalRepository(db),
+			Balance:       postgr.NewBalanceRepository(db),
+			Idempotency:   postgr.NewIdempotencyRepository(db),
+			Sessions:      postgr.NewSessionRepository(db),
+			Admin:         postgr.NewAdminRepository(db),
+			LoginAttempts: postgr.NewLoginAttemptRepository(db),
+			TwoFactor:     postgr.NewTwoFactorRepository(db),
+		},
+		checks: []services.HealthCheck{
+			{Name: "database", Critical: true, Check: db.PingContext},
+			{Name: "migrations", Critical: true, Check: func(ctx context.Context) error {
+				return postgr.CheckMigrations(ctx, db)
+			}},
+		},
+		collectors: []prometheus.Collector{collectors.NewDBStatsCollector(db.DB, "postgres")},
+		close:      db.Close,
+	}, nil
+}
+
+func newSQLiteStorage(ctx context.Context, dsn string) (*storage, error) {
+	db, err := sqlite.NewConnection(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &storage{
+		repos: &services.Repositories{
+			Users:         sqlite.NewUserRepository(db),
+			Orders:        sqlite.NewOrderRepository(db),
+			Withdrawals:   sqlite.NewWithdrawalRepository(db),
+			Balance:       sqlite.NewBalanceRepository(db),
+			Idempotency:   sqlite.NewIdempotencyRepository(db),
+			Sessions:      sqlite.NewSessionRepository(db),
+			Admin:         sqlite.NewAdminRepository(db),
+			LoginAttempts: sqlite.NewLoginAttemptRepository(db),
+			TwoFactor:     sqlite.NewTwoFactorRepository(db),
+		},
+		checks: []services.HealthCheck{
+			{Name: "database", Critical: true, Check: db.PingContext},
+			{Name: "migrations", Critical: true, Check: func(ctx context.Context) error {
+				return sqlite.CheckMigrations(ctx, db)
+			}},
+		},
+		collectors: []prometheus.Collector{collectors.NewDBStatsCollector(db.DB, "sqlite")},
+		close:      db.Close,
+	}, nil
+}
+
+// newMemoryStorage keeps everything in process memory, the data is lost on restart.
+func newMemoryStorage() *storage {
+	s := memory.New()
+	return &storage{
+		repos: &services.Repositories{
+			Users:         memory.NewUserRepository(s),
+			Orders:        memory.NewOrderRepository(s),
+			Withdrawals:   memory.NewWithdrawalRepository(s),
+			Balance:       memory.NewBalanceRepository(s),
+			Idempotency:   memory.NewIdempotencyRepository(s),
+			Sessions:      memory.NewSessionRepository(s),
+			Admin:         memory.NewAdminRepository(s),
+			LoginAttempts: memory.NewLoginAttemptRepository(s),
+			TwoFactor:     memory.NewTwoFactorRepository(s),
+		},
+		close: func() error { return nil },
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig has the flag defaults of every validated setting.
+func validConfig() *Config {
+	return &Config{
+		TokenExpMinutes:            10,
+		RefreshExpHours:            720,
+		AccrualWorkers:             5,
+		AccrualPollIntervalSeconds: 10,
+		AccrualBatchSize:           100,
+		AccrualTimeoutSeconds:      10,
+		AccrualMaxRetries:          3,
+		AccrualRetryDelaySeconds:   60,
+		IdempotencyTTLHours:        24,
+		LoginMaxAttempts:           5,
+		LoginIPMaxAttempts:         50,
+		LoginLockoutMinutes:        15,
+		PasswordMinLength:          8,
+		PasswordMinClasses:         2,
+		PasswordHash:               "argon2id",
+		BcryptCost:                 10,
+		Argon2MemoryKiB:            64 * 1024,
+		Argon2Iterations:           3,
+		Argon2Parallelism:          2,
+		ShutdownDelaySeconds:       5,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(c *Config)
+		valid     bool
+	}{
+		{name: "значения по умолчанию", configure: func(c *Config) {}, valid: true},
+		{name: "ноль воркеров начислений", configure: func(c *Config) { c.AccrualWorkers = 0 }},
+		{name: "один воркер начислений", configure: func(c *Config) { c.AccrualWorkers = 1 }, valid: true},
+		{name: "нулевой интервал опроса", configure: func(c *Config) { c.AccrualPollIntervalSeconds = 0 }},
+		{name: "интервал опроса в секунду", configure: func(c *Config) { c.AccrualPollIntervalSeconds = 1 }, valid: true},
+		{name: "нулевой размер пачки", configure: func(c *Config) { c.AccrualBatchSize = 0 }},
+		{name: "пачка из одного заказа", configure: func(c *Config) { c.AccrualBatchSize = 1 }, valid: true},
+		{name: "нулевой таймаут", configure: func(c *Config) { c.AccrualTimeoutSeconds = 0 }},
+		{name: "таймаут в секунду", configure: func(c *Config) { c.AccrualTimeoutSeconds = 1 }, valid: true},
+		{name: "ноль повторов", configure: func(c *Config) { c.AccrualMaxRetries = 0 }},
+		{name: "один повтор", configure: func(c *Config) { c.AccrualMaxRetries = 1 }, valid: true},
+		{name: "отрицательная пауза после 429", configure: func(c *Config) { c.AccrualRetryDelaySeconds = -1 }},
+		{name: "нулевая пауза после 429", configure: func(c *Config) { c.AccrualRetryDelaySeconds = 0 }, valid: true},
+		{name: "время жизни токена ноль", configure: func(c *Config) { c.TokenExpMinutes = 0 }},
+		{name: "время жизни токена переполняет Duration", configure: func(c *Config) { c.TokenExpMinutes = maxMinutes; c.TokenExpMinutes++ }},
+		{name: "время жизни refresh-токена ноль", configure: func(c *Config) { c.RefreshExpHours = 0 }},
+		{name: "время жизни refresh-токена переполняет Duration", configure: func(c *Config) { c.RefreshExpHours = maxHours; c.RefreshExpHours++ }},
+		{name: "интервал опроса переполняет Duration", configure: func(c *Config) { c.AccrualPollIntervalSeconds = maxSeconds; c.AccrualPollIntervalSeconds++ }},
+		{name: "нулевое время хранения ключей идемпотентности", configure: func(c *Config) { c.IdempotencyTTLHours = 0 }},
+		{name: "ноль попыток входа", configure: func(c *Config) { c.LoginMaxAttempts = 0 }},
+		{name: "одна попытка входа", configure: func(c *Config) { c.LoginMaxAttempts = 1 }, valid: true},
+		{name: "ноль попыток входа с ip", configure: func(c *Config) { c.LoginIPMaxAttempts = 0 }},
+		{name: "нулевая блокировка", configure: func(c *Config) { c.LoginLockoutMinutes = 0 }},
+		{name: "блокировка на минуту", configure: func(c *Config) { c.LoginLockoutMinutes = 1 }, valid: true},
+		{name: "нулевая длина пароля", configure: func(c *Config) { c.PasswordMinLength = 0 }},
+		{name: "длина пароля на пределе", configure: func(c *Config) { c.PasswordMinLength = 72 }, valid: true},
+		{name: "длина пароля больше предела", configure: func(c *Config) { c.PasswordMinLength = 73 }},
+		{name: "ноль классов символов", configure: func(c *Config) { c.PasswordMinClasses = 0 }},
+		{name: "все классы символов", configure: func(c *Config) { c.PasswordMinClasses = 4 }, valid: true},
+		{name: "классов символов больше четырех", configure: func(c *Config) { c.PasswordMinClasses = 5 }},
+		{name: "отрицательная задержка остановки", configure: func(c *Config) { c.ShutdownDelaySeconds = -1 }},
+		{name: "остановка без задержки", configure: func(c *Config) { c.ShutdownDelaySeconds = 0 }, valid: true},
+		{name: "bcrypt", configure: func(c *Config) { c.PasswordHash = "bcrypt" }, valid: true},
+		{name: "неизвестный алгоритм хеширования", configure: func(c *Config) { c.PasswordHash = "md5" }},
+		{name: "пустой алгоритм хеширования", configure: func(c *Config) { c.PasswordHash = "" }},
+		{name: "минимальная стоимость bcrypt", configure: func(c *Config) { c.BcryptCost = 4 }, valid: true},
+		{name: "максимальная стоимость bcrypt", configure: func(c *Config) { c.BcryptCost = 31 }, valid: true},
+		{name: "стоимость bcrypt ниже минимума", configure: func(c *Config) { c.BcryptCost = 3 }},
+		{name: "стоимость bcrypt выше максимума", configure: func(c *Config) { c.BcryptCost = 32 }},
+		{name: "параллелизм argon2 ноль", configure: func(c *Config) { c.Argon2Parallelism = 0 }},
+		{name: "параллелизм argon2 больше uint8", configure: func(c *Config) { c.Argon2Parallelism = 256 }},
+		{name: "максимальный параллелизм argon2", configure: func(c *Config) {
+			c.Argon2Parallelism = 255
+			c.Argon2MemoryKiB = 8 * 255
+		}, valid: true},
+		{name: "итерации argon2 ноль", configure: func(c *Config) { c.Argon2Iterations = 0 }},
+		{name: "отрицательная память argon2", configure: func(c *Config) { c.Argon2MemoryKiB = -1 }},
+		{name: "память argon2 меньше 8 KiB на поток", configure: func(c *Config) { c.Argon2MemoryKiB = 15 }},
+		{name: "память argon2 ровно 8 KiB на поток", configure: func(c *Config) { c.Argon2MemoryKiB = 16 }, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.configure(c)
+
+			err := c.Validate()
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, errs.ErrConfigInvalid)
+			}
+		})
+	}
+}
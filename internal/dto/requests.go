@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/Soliard/gophermart/internal/models"
+
 type RegisterRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
@@ -10,13 +12,45 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type GrantRoleRequest struct {
+	Role models.Role `json:"role"`
+}
+
+type BlockUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+type BalanceAdjustmentRequest struct {
+	Amount models.Points `json:"amount"`
+	Reason string        `json:"reason"`
+	Ticket string        `json:"ticket"`
+}
+
 type WithdrawalRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string        `json:"order"`
+	Sum   models.Points `json:"sum"`
 }
 
 type AccrualOrder struct {
-	Order   string   `json:"order"`
-	Status  string   `json:"status"`
-	Accrual *float64 `json:"accrual,omitempty"`
+	Order   string         `json:"order"`
+	Status  string         `json:"status"`
+	Accrual *models.Points `json:"accrual,omitempty"`
 }
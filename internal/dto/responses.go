@@ -0,0 +1,26 @@
+package dto
+
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	Type           string `json:"type"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type HealthResponse struct {
+	Status string                        `json:"status"`
+	Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+}
+
+type HealthCheckResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
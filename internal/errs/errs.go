@@ -7,22 +7,83 @@ var (
 	ErrEmptyLoginOrPassword = errors.New("login or password is empty")
 	ErrUserAlreadyExists    = errors.New("login already exists")
 	ErrWrongLoginOrPassword = errors.New("wrong login or password")
+	ErrUserBlocked          = errors.New("user is blocked")
+	ErrLoginFormatInvalid   = errors.New("login may contain only latin letters, digits and ._@+- and be 3 to 64 characters long")
+	ErrWrongPassword        = errors.New("wrong password")
+
+	ErrPasswordTooShort  = errors.New("password is too short")
+	ErrPasswordTooLong   = errors.New("password must not be longer than 72 bytes")
+	ErrPasswordTooWeak   = errors.New("password must contain more character classes")
+	ErrPasswordTooCommon = errors.New("password is too common")
+	ErrPasswordUnchanged = errors.New("new password must differ from the old one")
+
+	ErrPasswordHashUnknown          = errors.New("unknown password hash format")
+	ErrPasswordHashInvalid          = errors.New("malformed password hash")
+	ErrPasswordHashAlgorithmUnknown = errors.New("unknown password hash algorithm")
+
+	ErrConfigInvalid          = errors.New("invalid config")
+	ErrTracingExporterUnknown = errors.New("unknown tracing exporter")
+	ErrStorageBackendUnknown  = errors.New("unknown storage backend")
+	ErrDatabaseURIInvalid     = errors.New("unsupported database uri")
+
+	ErrShuttingDown         = errors.New("server is shutting down")
+	ErrMigrationsNotApplied = errors.New("database migrations are not applied")
+	ErrMigrationsDirty      = errors.New("database migration failed halfway")
+	ErrAccrualPollStale     = errors.New("no successful accrual poll for too long")
+	ErrAccrualUnreachable   = errors.New("accrual system is unreachable")
+
+	ErrAccrualSimRuleInvalid = errors.New("invalid accrual simulator rule")
+
+	ErrLoginLocked  = errors.New("too many failed login attempts")
+	ErrLoginTooLong = errors.New("login is too long")
+
+	ErrTwoFactorRequired       = errors.New("two-factor authentication code required")
+	ErrTwoFactorCodeInvalid    = errors.New("invalid two-factor authentication code")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication enrollment not started")
+	ErrLoginAttemptsNotFound   = errors.New("login attempts not found")
+
+	ErrRoleUnknown     = errors.New("unknown role")
+	ErrAdminSelfAction = errors.New("administrator can not perform this action on own account")
 
 	ErrTokenExpired  = errors.New("jwt expired")
 	ErrTokenNotFound = errors.New("token not found in headers")
 	ErrTokenInvalid  = errors.New("invalid token")
 
+	ErrSigningKeyInvalid     = errors.New("invalid signing key")
+	ErrSigningKeyUnsupported = errors.New("unsupported signing key type")
+	ErrSigningKeyNotPrivate  = errors.New("active signing key must contain a private key")
+	ErrSigningKeyDuplicate   = errors.New("duplicate signing key id")
+
 	ErrEmptyContextUser = errors.New("user info not found in context")
 
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+
 	ErrOrderNotFound                   = errors.New("order not uploaded yet")
 	ErrOrderAlreadyUploadedByOtherUser = errors.New("order already uploaded by other user")
 	ErrOrderAlreadyUploadedByThisUser  = errors.New("order already uploaded by this user")
 	ErrOrderIsNotValid                 = errors.New("order is not valid")
+	ErrOrderStatusUnknown              = errors.New("unknown order status")
+	ErrOrderStatusTransition           = errors.New("order status transition is not allowed")
+	ErrOrderAlreadyFinal               = errors.New("order already has final status")
 
 	ErrBalanceInsufficient = errors.New("not enough points on balance")
 
+	ErrAdjustmentAmountInvalid  = errors.New("adjustment amount must be non-zero")
+	ErrAdjustmentReasonRequired = errors.New("adjustment reason is required")
+	ErrAdjustmentTicketRequired = errors.New("adjustment ticket reference is required")
+
 	ErrWithdrawalAlreadyProcessed = errors.New("this withdraw already was processed")
 	ErrWithdrawalsNotFound        = errors.New("withdrawals not found")
 
+	ErrIdempotencyKeyNotFound   = errors.New("idempotency key not found")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyTooLong    = errors.New("idempotency key is too long")
+	ErrIdempotencyLeaseLost     = errors.New("idempotency key lease was taken over")
+
 	ErrUnexpectedStatusAccrualService = errors.New("unexpected status code from accrual service")
+	ErrOrderNotRegisteredInAccrual    = errors.New("order is not registered in accrual service")
+	ErrAccrualRateLimited             = errors.New("accrual service rate limit exceeded")
 )
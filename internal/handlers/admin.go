@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+type adminHandler struct {
+	service services.AdminServiceInterface
+}
+
+func NewAdminHandler(adminService services.AdminServiceInterface) *adminHandler {
+	return &adminHandler{
+		service: adminService,
+	}
+}
+
+func (h *adminHandler) ListUsers(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	limit, offset, ok := parsePagination(req)
+	if !ok {
+		http.Error(res, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.service.ListUsers(ctx, req.URL.Query().Get("login"), limit, offset)
+	if err != nil {
+		log.Error("Failed to list users", logger.F.Error(err))
+		http.Error(res, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	if len(users) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, users)
+	if err != nil {
+		log.Error("Failed to send users", logger.F.Error(err))
+	}
+}
+
+func (h *adminHandler) GetUserOrders(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	orders, err := h.service.GetUserOrders(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			http.Error(res, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Error("Failed to get user orders", logger.F.Error(err))
+		http.Error(res, "Failed to get orders", http.StatusInternalServerError)
+		return
+	}
+
+	if len(orders) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, orders)
+	if err != nil {
+		log.Error("Failed to send orders", logger.F.Error(err))
+	}
+}
+
+func (h *adminHandler) GetUserWithdrawals(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := h.service.GetUserWithdrawals(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			http.Error(res, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Error("Failed to get user withdrawals", logger.F.Error(err))
+		http.Error(res, "Failed to get withdrawals", http.StatusInternalServerError)
+		return
+	}
+
+	if len(withdrawals) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, withdrawals)
+	if err != nil {
+		log.Error("Failed to send withdrawals", logger.F.Error(err))
+	}
+}
+
+func (h *adminHandler) GrantRole(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.GrantRoleRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+
+	h.runAction(res, req, func(actorID string) error {
+		return h.service.GrantRole(ctx, actorID, userID, reqData.Role)
+	})
+}
+
+func (h *adminHandler) RevokeRole(res http.ResponseWriter, req *http.Request) {
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	h.runAction(res, req, func(actorID string) error {
+		role := models.Role(chi.URLParam(req, "role"))
+		return h.service.RevokeRole(req.Context(), actorID, userID, role)
+	})
+}
+
+func (h *adminHandler) BlockUser(res http.ResponseWriter, req *http.Request) {
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	reqData := &dto.BlockUserRequest{}
+	if req.ContentLength != 0 {
+		err := json.NewDecoder(req.Body).Decode(reqData)
+		if err != nil {
+			http.Error(res, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.runAction(res, req, func(actorID string) error {
+		return h.service.BlockUser(req.Context(), actorID, userID, reqData.Reason)
+	})
+}
+
+func (h *adminHandler) UnblockUser(res http.ResponseWriter, req *http.Request) {
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	h.runAction(res, req, func(actorID string) error {
+		return h.service.UnblockUser(req.Context(), actorID, userID)
+	})
+}
+
+func (h *adminHandler) UnlockUser(res http.ResponseWriter, req *http.Request) {
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	h.runAction(res, req, func(actorID string) error {
+		return h.service.UnlockUser(req.Context(), actorID, userID)
+	})
+}
+
+func (h *adminHandler) RequeueOrder(res http.ResponseWriter, req *http.Request) {
+	h.runAction(res, req, func(actorID string) error {
+		return h.service.RequeueOrder(req.Context(), actorID, chi.URLParam(req, "number"))
+	})
+}
+
+func (h *adminHandler) AdjustBalance(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.BalanceAdjustmentRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	adj, err := h.service.AdjustBalance(ctx, userCtx.ID, userID, reqData)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrAdjustmentAmountInvalid),
+			errors.Is(err, errs.ErrAdjustmentReasonRequired),
+			errors.Is(err, errs.ErrAdjustmentTicketRequired):
+			http.Error(res, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, errs.ErrUserNotFound):
+			http.Error(res, "User not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrBalanceInsufficient):
+			http.Error(res, "Adjustment would make balance negative", http.StatusConflict)
+		default:
+			log.Error("Failed to adjust balance", logger.F.Error(err), logger.F.Any("admin", userCtx))
+			http.Error(res, "Failed to adjust balance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusCreated, adj)
+	if err != nil {
+		log.Error("Failed to send balance adjustment", logger.F.Error(err))
+	}
+}
+
+func (h *adminHandler) GetBalanceAdjustments(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userID, ok := userIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	adjustments, err := h.service.GetBalanceAdjustments(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			http.Error(res, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Error("Failed to get balance adjustments", logger.F.Error(err))
+		http.Error(res, "Failed to get balance adjustments", http.StatusInternalServerError)
+		return
+	}
+
+	if len(adjustments) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, adjustments)
+	if err != nil {
+		log.Error("Failed to send balance adjustments", logger.F.Error(err))
+	}
+}
+
+func (h *adminHandler) GetAuditLog(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	limit, offset, ok := parsePagination(req)
+	if !ok {
+		http.Error(res, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.service.GetAuditLog(ctx, limit, offset)
+	if err != nil {
+		log.Error("Failed to get audit log", logger.F.Error(err))
+		http.Error(res, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+
+	if len(entries) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, entries)
+	if err != nil {
+		log.Error("Failed to send audit log", logger.F.Error(err))
+	}
+}
+
+// runAction executes an audited admin action on behalf of the authenticated
+// administrator and maps its errors to responses.
+func (h *adminHandler) runAction(res http.ResponseWriter, req *http.Request, action func(actorID string) error) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	err = action(userCtx.ID)
+	switch {
+	case err == nil:
+		res.WriteHeader(http.StatusOK)
+	case errors.Is(err, errs.ErrUserNotFound):
+		http.Error(res, "User not found", http.StatusNotFound)
+	case errors.Is(err, errs.ErrOrderNotFound):
+		http.Error(res, "Order not found", http.StatusNotFound)
+	case errors.Is(err, errs.ErrRoleUnknown):
+		http.Error(res, "Unknown role", http.StatusBadRequest)
+	case errors.Is(err, errs.ErrAdminSelfAction):
+		http.Error(res, "Action is not allowed on own account", http.StatusConflict)
+	case errors.Is(err, errs.ErrOrderAlreadyFinal):
+		http.Error(res, "Order already has final status", http.StatusConflict)
+	default:
+		log.Error("Failed to run admin action", logger.F.Error(err), logger.F.Any("admin", userCtx))
+		http.Error(res, "Failed to run admin action", http.StatusInternalServerError)
+	}
+}
+
+// userIDParam returns the userID URL parameter and answers 400 if it is not a
+// UUID, so that malformed ids do not reach the storage.
+func userIDParam(res http.ResponseWriter, req *http.Request) (string, bool) {
+	userID := chi.URLParam(req, "userID")
+	if _, err := uuid.Parse(userID); err != nil {
+		http.Error(res, "Invalid user id", http.StatusBadRequest)
+		return "", false
+	}
+	return userID, true
+}
+
+func parsePagination(req *http.Request) (limit, offset int, ok bool) {
+	limit, offset = defaultPageLimit, 0
+	query := req.URL.Query()
+	var err error
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, false
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, false
+		}
+	}
+	return limit, offset, true
+}
@@ -40,3 +40,32 @@ func (h *balanceHandler) GetBalance(res http.ResponseWriter, req *http.Request)
 		log.Error("Failed to send balance", logger.F.Error(err), logger.F.Any("user", userCtx))
 	}
 }
+
+func (h *balanceHandler) GetHistory(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := h.service.GetHistory(ctx, userCtx.ID)
+	if err != nil {
+		log.Error("Failed to get users balance history", logger.F.Error(err), logger.F.Any("user", userCtx))
+		http.Error(res, "Failed to get balance history", http.StatusInternalServerError)
+		return
+	}
+
+	if len(entries) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, entries)
+	if err != nil {
+		log.Error("Failed to send balance history", logger.F.Error(err), logger.F.Any("user", userCtx))
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+type mockBalanceService struct {
+	mock.Mock
+}
+
+func (m *mockBalanceService) GetBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Balance), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockBalanceService) GetHistory(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.LedgerEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockBalanceService) Reconcile(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	args := m.Called(ctx)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.BalanceMismatch), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestBalanceHandler_GetHistory(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	history := []*models.LedgerEntry{
+		{Kind: models.LedgerEntryWithdrawal, OrderNumber: "2377225624", Amount: -20000, BalanceAfter: 30000, CreatedAt: now},
+		{Kind: models.LedgerEntryAccrual, OrderNumber: "12345678903", Amount: 50000, BalanceAfter: 50000, CreatedAt: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name           string
+		entries        []*models.LedgerEntry
+		serviceErr     error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "история от новых записей к старым",
+			entries:        history,
+			expectedStatus: http.StatusOK,
+			expectedBody: `[
+				{"kind": "withdrawal", "order": "2377225624", "amount": -200, "balance": 300, "created_at": "2026-01-02T03:04:05Z"},
+				{"kind": "accrual", "order": "12345678903", "amount": 500, "balance": 500, "created_at": "2026-01-02T02:04:05Z"}
+			]`,
+		},
+		{
+			name:           "пустая история",
+			entries:        []*models.LedgerEntry{},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "ошибка сервиса",
+			serviceErr:     errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(mockBalanceService)
+			service.On("GetHistory", mock.Anything, "user123").Return(tt.entries, tt.serviceErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/balance/history", nil)
+			req = req.WithContext(services.ContextWithUser(req.Context(), &services.UserContext{ID: "user123"}))
+			res := httptest.NewRecorder()
+			NewBalanceHandler(service).GetHistory(res, req)
+
+			require.Equal(t, tt.expectedStatus, res.Code)
+			if tt.expectedBody != "" {
+				require.Equal(t, "application/json", res.Header().Get("Content-Type"))
+				require.JSONEq(t, tt.expectedBody, res.Body.String())
+			}
+			if tt.expectedStatus == http.StatusNoContent {
+				require.Empty(t, res.Body.String())
+			}
+			service.AssertExpectations(t)
+		})
+	}
+
+	t.Run("без пользователя в контексте", func(t *testing.T) {
+		service := new(mockBalanceService)
+		res := httptest.NewRecorder()
+		NewBalanceHandler(service).GetHistory(res, httptest.NewRequest(http.MethodGet, "/api/user/balance/history", nil))
+
+		require.Equal(t, http.StatusInternalServerError, res.Code)
+		service.AssertNotCalled(t, "GetHistory", mock.Anything, mock.Anything)
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strings"
 
@@ -13,14 +14,22 @@ type Handlers struct {
 	Order      *orderHandler
 	Balance    *balanceHandler
 	Withdrawal *withdrawalHandler
+	JWKS       *jwksHandler
+	Admin      *adminHandler
+	TwoFactor  *twoFactorHandler
+	Health     *healthHandler
 }
 
 func New(services *services.Services) *Handlers {
 	return &Handlers{
-		User:       NewUserHandler(services.Reg, services.Auth),
+		User:       NewUserHandler(services.Reg, services.Auth, services.Session, services.Password),
 		Order:      NewOrderHandler(services.Order),
 		Balance:    NewBalanceHandler(services.Balance),
 		Withdrawal: NewWithdrawalHandler(services.Withdrawal),
+		JWKS:       NewJWKSHandler(services.JWT),
+		Admin:      NewAdminHandler(services.Admin),
+		TwoFactor:  NewTwoFactorHandler(services.TwoFactor),
+		Health:     NewHealthHandler(services.Health),
 	}
 }
 
@@ -37,6 +46,16 @@ func validateTextContentType(req *http.Request) bool {
 	return validateContentType(req, "text/plain")
 }
 
+// clientIP returns the address of the direct peer. Forwarding headers are
+// ignored on purpose, they are trivially spoofed by the client.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
 func handleJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
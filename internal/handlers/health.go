@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type healthHandler struct {
+	service services.HealthServiceInterface
+}
+
+func NewHealthHandler(service services.HealthServiceInterface) *healthHandler {
+	return &healthHandler{
+		service: service,
+	}
+}
+
+// Liveness only tells that the process serves HTTP, dependencies are not checked.
+func (h *healthHandler) Liveness(res http.ResponseWriter, req *http.Request) {
+	err := handleJSONResponse(res, http.StatusOK, &dto.HealthResponse{Status: services.HealthStatusOK})
+	if err != nil {
+		logger.FromContext(req.Context()).Error("Failed to marshal body", logger.F.Error(err))
+	}
+}
+
+func (h *healthHandler) Readiness(res http.ResponseWriter, req *http.Request) {
+	health := h.service.Readiness(req.Context())
+	status := http.StatusOK
+	if health.Status != services.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	err := handleJSONResponse(res, status, health)
+	if err != nil {
+		logger.FromContext(req.Context()).Error("Failed to marshal body", logger.F.Error(err))
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type jwksHandler struct {
+	service services.JWTServiceInterface
+}
+
+func NewJWKSHandler(jwtService services.JWTServiceInterface) *jwksHandler {
+	return &jwksHandler{
+		service: jwtService,
+	}
+}
+
+func (h *jwksHandler) GetJWKS(res http.ResponseWriter, req *http.Request) {
+	log := logger.FromContext(req.Context())
+
+	res.Header().Set("Cache-Control", "public, max-age=300")
+	err := handleJSONResponse(res, http.StatusOK, h.service.JWKS())
+	if err != nil {
+		log.Error("Failed to send jwks", logger.F.Error(err))
+	}
+}
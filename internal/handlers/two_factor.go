@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type twoFactorHandler struct {
+	service services.TwoFactorServiceInterface
+}
+
+func NewTwoFactorHandler(service services.TwoFactorServiceInterface) *twoFactorHandler {
+	return &twoFactorHandler{
+		service: service,
+	}
+}
+
+func (h *twoFactorHandler) Enroll(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, err := h.service.Enroll(ctx, userCtx.ID)
+	if err != nil {
+		if errors.Is(err, errs.ErrTwoFactorAlreadyEnabled) {
+			http.Error(res, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		log.Error("Failed to enroll two-factor authentication", logger.F.Error(err), logger.F.Any("user", userCtx))
+		http.Error(res, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, enrollment)
+	if err != nil {
+		log.Error("Failed to marshal body", logger.F.Error(err))
+	}
+}
+
+func (h *twoFactorHandler) Confirm(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.TwoFactorCodeRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+	if reqData.Code == "" {
+		http.Error(res, "Code must be not empty", http.StatusBadRequest)
+		return
+	}
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := h.service.Confirm(ctx, userCtx.ID, reqData.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrTwoFactorAlreadyEnabled):
+			http.Error(res, "Two-factor authentication is already enabled", http.StatusConflict)
+		case errors.Is(err, errs.ErrTwoFactorNotEnrolled):
+			http.Error(res, "Two-factor enrollment was not started", http.StatusConflict)
+		case errors.Is(err, errs.ErrTwoFactorCodeInvalid):
+			http.Error(res, "Invalid two-factor code", http.StatusUnprocessableEntity)
+		default:
+			log.Error("Failed to confirm two-factor authentication", logger.F.Error(err), logger.F.Any("user", userCtx))
+			http.Error(res, "Failed to confirm two-factor authentication", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = handleJSONResponse(res, http.StatusOK, &dto.RecoveryCodes{RecoveryCodes: codes})
+	if err != nil {
+		log.Error("Failed to marshal body", logger.F.Error(err))
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/Soliard/gophermart/internal/dto"
 	"github.com/Soliard/gophermart/internal/errs"
@@ -11,15 +13,26 @@ import (
 	"github.com/Soliard/gophermart/internal/services"
 )
 
+const refreshTokenHeader = "Refresh-Token"
+
 type userHandler struct {
-	reg  services.RegistrationServiceInterface
-	auth services.AuthServiceInterface
+	reg      services.RegistrationServiceInterface
+	auth     services.AuthServiceInterface
+	sessions services.SessionServiceInterface
+	password services.PasswordServiceInterface
 }
 
-func NewUserHandler(reg services.RegistrationServiceInterface, auth services.AuthServiceInterface) *userHandler {
+func NewUserHandler(
+	reg services.RegistrationServiceInterface,
+	auth services.AuthServiceInterface,
+	sessions services.SessionServiceInterface,
+	password services.PasswordServiceInterface) *userHandler {
+
 	return &userHandler{
-		reg:  reg,
-		auth: auth,
+		reg:      reg,
+		auth:     auth,
+		sessions: sessions,
+		password: password,
 	}
 }
 
@@ -50,24 +63,22 @@ func (h *userHandler) Register(res http.ResponseWriter, req *http.Request) {
 			http.Error(res, "Login and password must be not empty", http.StatusBadRequest)
 			return
 		}
+		if isCredentialsPolicyError(err) {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Error("Failed to register user", logger.F.Error(err))
 		http.Error(res, "Failed to register user", http.StatusInternalServerError)
 		return
 	}
 
-	logData := &dto.LoginRequest{Login: regData.Login, Password: regData.Password}
-	token, err := h.auth.Login(ctx, logData)
+	tokens, err := h.sessions.Start(ctx, u)
 	if err != nil {
-		log.Error("Failed to login after register (shouldnt happen)",
-			logger.F.Error(err),
-			logger.F.Any("regData", regData),
-			logger.F.Any("user", u),
-			logger.F.Any("logData", logData),
-		)
+		log.Error("Failed to start session after registration", logger.F.Error(err), logger.F.String("user", u.ID))
 		http.Error(res, "Failed to login after registration", http.StatusInternalServerError)
 		return
 	}
-	res.Header().Add("Authorization", token)
+	setTokenHeaders(res, tokens)
 	err = handleJSONResponse(res, http.StatusOK, u)
 	if err != nil {
 		log.Error("Failed to marshal body", logger.F.Error(err))
@@ -95,17 +106,222 @@ func (h *userHandler) Login(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	token, err := h.auth.Login(ctx, logData)
+	tokens, err := h.auth.Login(ctx, logData, clientIP(req))
 	if err != nil {
+		var twoFactor *services.TwoFactorRequiredError
+		if errors.As(err, &twoFactor) {
+			challenge := &dto.TwoFactorChallenge{ChallengeToken: twoFactor.ChallengeToken, Type: "totp"}
+			err = handleJSONResponse(res, http.StatusAccepted, challenge)
+			if err != nil {
+				log.Error("Failed to marshal body", logger.F.Error(err))
+			}
+			return
+		}
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			http.Error(res, "Too many failed login attempts, try later", http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, errs.ErrLoginTooLong) {
+			http.Error(res, "Login is too long", http.StatusBadRequest)
+			return
+		}
 		if errors.Is(err, errs.ErrWrongLoginOrPassword) {
 			http.Error(res, "Wrong login or password", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, errs.ErrUserBlocked) {
+			http.Error(res, "User is blocked", http.StatusForbidden)
+			return
+		}
 		log.Error("Failed to login user", logger.F.Error(err))
 		http.Error(res, "Failed to login, try later", http.StatusInternalServerError)
 		return
 	}
 
-	res.Header().Add("Authorization", token)
+	setTokenHeaders(res, tokens)
+	res.WriteHeader(http.StatusOK)
+}
+
+func (h *userHandler) LoginTwoFactor(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.TwoFactorLoginRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+	if reqData.ChallengeToken == "" || reqData.Code == "" {
+		http.Error(res, "Challenge token and code must be not empty", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.auth.LoginTwoFactor(ctx, reqData, clientIP(req))
+	if err != nil {
+		var locked *services.LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			http.Error(res, "Too many failed login attempts, try later", http.StatusTooManyRequests)
+		case errors.Is(err, errs.ErrTokenInvalid):
+			http.Error(res, "Invalid or expired challenge token", http.StatusUnauthorized)
+		case errors.Is(err, errs.ErrTwoFactorCodeInvalid):
+			http.Error(res, "Invalid two-factor code", http.StatusUnauthorized)
+		case errors.Is(err, errs.ErrUserBlocked):
+			http.Error(res, "User is blocked", http.StatusForbidden)
+		default:
+			log.Error("Failed to complete two-factor login", logger.F.Error(err))
+			http.Error(res, "Failed to login, try later", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setTokenHeaders(res, tokens)
 	res.WriteHeader(http.StatusOK)
 }
+
+func (h *userHandler) RefreshToken(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.RefreshTokenRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+	if reqData.RefreshToken == "" {
+		http.Error(res, "Refresh token must be not empty", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.sessions.Refresh(ctx, reqData.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errs.ErrRefreshTokenInvalid) {
+			http.Error(res, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		log.Error("Failed to refresh token", logger.F.Error(err))
+		http.Error(res, "Failed to refresh token, try later", http.StatusInternalServerError)
+		return
+	}
+
+	setTokenHeaders(res, tokens)
+	res.WriteHeader(http.StatusOK)
+}
+
+func (h *userHandler) ChangePassword(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	if !validateJSONContentType(req) {
+		http.Error(res, "Only application/json is allowed", http.StatusBadRequest)
+		return
+	}
+
+	reqData := &dto.ChangePasswordRequest{}
+	err := json.NewDecoder(req.Body).Decode(reqData)
+	if err != nil {
+		log.Error("Failed to decode body", logger.F.Error(err))
+		http.Error(res, "Failed to decode body", http.StatusBadRequest)
+		return
+	}
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := h.password.ChangePassword(ctx, userCtx.ID, clientIP(req), reqData)
+	if err != nil {
+		var locked *services.LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			http.Error(res, "Too many failed attempts, try later", http.StatusTooManyRequests)
+		case errors.Is(err, errs.ErrWrongPassword):
+			http.Error(res, "Wrong password", http.StatusForbidden)
+		case errors.Is(err, errs.ErrPasswordUnchanged), isCredentialsPolicyError(err):
+			http.Error(res, err.Error(), http.StatusBadRequest)
+		default:
+			log.Error("Failed to change password", logger.F.Error(err), logger.F.Any("user", userCtx))
+			http.Error(res, "Failed to change password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setTokenHeaders(res, tokens)
+	res.WriteHeader(http.StatusOK)
+}
+
+func (h *userHandler) Logout(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.sessions.Revoke(ctx, userCtx.SessionID)
+	if err != nil {
+		log.Error("Failed to revoke session", logger.F.Error(err), logger.F.Any("user", userCtx))
+		http.Error(res, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+func (h *userHandler) LogoutAll(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := logger.FromContext(ctx)
+
+	userCtx, err := services.GetUserFromContext(ctx)
+	if err != nil {
+		log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+		http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.sessions.RevokeAll(ctx, userCtx.ID)
+	if err != nil {
+		log.Error("Failed to revoke user sessions", logger.F.Error(err), logger.F.Any("user", userCtx))
+		http.Error(res, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+func setTokenHeaders(res http.ResponseWriter, tokens *services.Tokens) {
+	res.Header().Add("Authorization", tokens.Access)
+	res.Header().Add(refreshTokenHeader, tokens.Refresh)
+}
+
+func isCredentialsPolicyError(err error) bool {
+	return errors.Is(err, errs.ErrLoginFormatInvalid) ||
+		errors.Is(err, errs.ErrPasswordTooShort) ||
+		errors.Is(err, errs.ErrPasswordTooLong) ||
+		errors.Is(err, errs.ErrPasswordTooWeak) ||
+		errors.Is(err, errs.ErrPasswordTooCommon)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/Soliard/gophermart/internal/dto"
@@ -48,19 +49,25 @@ func (h *withdrawalHandler) ProcessWithdrawal(res http.ResponseWriter, req *http
 	}
 
 	err = h.service.ProcessWithdraw(ctx, userCtx.ID, reqData.Order, reqData.Sum)
-	switch err {
-	case errs.ErrOrderIsNotValid:
+	switch {
+	case err == nil:
+	case errors.Is(err, errs.ErrOrderIsNotValid):
 		http.Error(res, "Order number is not valid", http.StatusUnprocessableEntity)
 		return
 
-	case errs.ErrWithdrawalAlreadyProcessed:
+	case errors.Is(err, errs.ErrWithdrawalAlreadyProcessed):
 		log.Warn("Attempt withdrawal order that already has been withdrawed", logger.F.Any("request data", reqData))
 		res.WriteHeader(http.StatusOK)
 		return
 
-	case errs.ErrBalanceInsufficient:
+	case errors.Is(err, errs.ErrBalanceInsufficient):
 		http.Error(res, "Not enough points on balance", http.StatusPaymentRequired)
 		return
+
+	default:
+		log.Error("Failed to process withdrawal", logger.F.Error(err), logger.F.Any("request data", reqData))
+		http.Error(res, "Failed to process withdrawal", http.StatusInternalServerError)
+		return
 	}
 
 	res.WriteHeader(http.StatusOK)
@@ -4,10 +4,14 @@ import (
 	"context"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-const ctxKeyLogger ctxKey = "logger"
+const (
+	ctxKeyLogger  ctxKey = "logger"
+	ctxKeyTraceID ctxKey = "trace id"
+)
 
 var Log Logger
 var F = fieldBuilder{}
@@ -35,15 +39,31 @@ func (z zapWrapper) With(fields ...Field) Logger {
 	return zapWrapper{z.Logger.With(fields...)}
 }
 
+// WithContext stores the logger in ctx. The logger is expected to be derived
+// from FromContext(ctx), so it already has the trace id of the current span.
 func WithContext(ctx context.Context, logger Logger) context.Context {
-	return context.WithValue(ctx, ctxKeyLogger, logger)
+	ctx = context.WithValue(ctx, ctxKeyLogger, logger)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		ctx = context.WithValue(ctx, ctxKeyTraceID, sc.TraceID())
+	}
+	return ctx
 }
 
+// FromContext returns the logger stored in ctx with the trace id of the
+// current span added, unless the stored logger already has it.
 func FromContext(ctx context.Context) Logger {
-	if logger, ok := ctx.Value(ctxKeyLogger).(Logger); ok {
+	logger, ok := ctx.Value(ctxKeyLogger).(Logger)
+	if !ok {
+		logger = Log
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logger
+	}
+	if traceID, ok := ctx.Value(ctxKeyTraceID).(trace.TraceID); ok && traceID == sc.TraceID() {
 		return logger
 	}
-	return Log
+	return logger.With(F.String("trace_id", sc.TraceID().String()))
 }
 
 func Init() error {
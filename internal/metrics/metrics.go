@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gophermart"
+
+// registry holds the process wide metrics. Collectors bound to an App
+// instance, like the DB pool stats, are passed to Handler instead, so that
+// several instances can live in one process.
+var registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	AccrualOrdersPolled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "orders_polled_total",
+		Help:      "Orders claimed for polling the accrual system.",
+	})
+
+	AccrualResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "responses_total",
+		Help:      "Responses of the accrual system by status code.",
+	}, []string{"status"})
+
+	AccrualErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "errors_total",
+		Help:      "Failed order updates from the accrual system.",
+	})
+
+	OrdersUploaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_uploaded_total",
+		Help:      "Orders uploaded by users.",
+	})
+
+	Withdrawals = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "withdrawals_total",
+		Help:      "Successful withdrawals.",
+	})
+
+	PointsWithdrawn = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_withdrawn_total",
+		Help:      "Loyalty points spent in successful withdrawals.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		AccrualOrdersPolled,
+		AccrualResponses,
+		AccrualErrors,
+		OrdersUploaded,
+		Withdrawals,
+		PointsWithdrawn,
+	)
+}
+
+// Handler serves the process wide metrics together with the given collectors.
+func Handler(extra ...prometheus.Collector) http.Handler {
+	local := prometheus.NewRegistry()
+	local.MustRegister(extra...)
+	return promhttp.HandlerFor(prometheus.Gatherers{registry, local}, promhttp.HandlerOpts{})
+}
+
+type OrderStatusCounter interface {
+	CountOrdersByStatus(ctx context.Context) (map[models.OrderStatus]int, error)
+}
+
+const queueScrapeTimeout = 5 * time.Second
+
+var queueDepthDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "accrual", "queue_depth"),
+	"Orders by status. Orders in non final statuses are waiting for the accrual system.",
+	[]string{"status"}, nil,
+)
+
+type orderQueueCollector struct {
+	counter OrderStatusCounter
+}
+
+// NewOrderQueueCollector queries the order counts on every scrape.
+func NewOrderQueueCollector(counter OrderStatusCounter) prometheus.Collector {
+	return &orderQueueCollector{counter: counter}
+}
+
+func (c *orderQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+func (c *orderQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueScrapeTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountOrdersByStatus(ctx)
+	if err != nil {
+		logger.Log.Error("Failed to count orders by status", logger.F.Error(err))
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+		return
+	}
+	for _, status := range []models.OrderStatus{
+		models.StatusNew, models.StatusProcessing, models.StatusInvalid, models.StatusProcessed,
+	} {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
@@ -12,7 +12,10 @@ import (
 const bearerPrefix = "Bearer "
 const authHeader = "Authorization"
 
-func Authentication(jwtService services.JWTServiceInterface) func(http.Handler) http.Handler {
+func Authentication(
+	jwtService services.JWTServiceInterface,
+	sessions services.SessionServiceInterface) func(http.Handler) http.Handler {
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 			ctx := req.Context()
@@ -30,6 +33,17 @@ func Authentication(jwtService services.JWTServiceInterface) func(http.Handler)
 				return
 			}
 
+			active, err := sessions.IsActive(ctx, userInfo.SessionID)
+			if err != nil {
+				log.Error("Failed to check session", logger.F.Error(err))
+				http.Error(res, "Failed to check session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(res, "Session is revoked", http.StatusUnauthorized)
+				return
+			}
+
 			ctx = services.ContextWithUser(ctx, userInfo)
 
 			next.ServeHTTP(res, req.WithContext(ctx))
@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotentBodyBytes bounds the body read into memory to hash the request.
+const maxIdempotentBodyBytes = 1 << 20
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordingResponseWriter) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recordingResponseWriter) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Idempotency replays the stored response when a request is repeated with the
+// same Idempotency-Key. Requests without the header are passed through.
+func Idempotency(idempotency services.IdempotencyServiceInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			key := req.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(res, req)
+				return
+			}
+
+			ctx := req.Context()
+			log := logger.FromContext(ctx)
+
+			userCtx, err := services.GetUserFromContext(ctx)
+			if err != nil {
+				log.Error("Failed to get user context from ctx after authentication", logger.F.Error(err))
+				http.Error(res, "Failed to get user context", http.StatusInternalServerError)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(res, req.Body, maxIdempotentBodyBytes))
+			req.Body.Close()
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(res, "Request body is too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				log.Error("Failed to read body", logger.F.Error(err))
+				http.Error(res, "Failed to read body", http.StatusInternalServerError)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			rec, err := idempotency.Begin(ctx, userCtx.ID, key, requestHash(req, body))
+			switch {
+			case err == nil:
+			case errors.Is(err, errs.ErrIdempotencyKeyTooLong):
+				http.Error(res, "Idempotency key is too long", http.StatusBadRequest)
+				return
+			case errors.Is(err, errs.ErrIdempotencyKeyReused):
+				http.Error(res, "Idempotency key was used for a different request", http.StatusUnprocessableEntity)
+				return
+			case errors.Is(err, errs.ErrIdempotencyKeyInProgress):
+				http.Error(res, "Request with this idempotency key is in progress", http.StatusConflict)
+				return
+			default:
+				log.Error("Failed to check idempotency key", logger.F.Error(err))
+				http.Error(res, "Failed to check idempotency key", http.StatusInternalServerError)
+				return
+			}
+
+			if rec.Completed() {
+				replay(res, rec)
+				return
+			}
+
+			// The key is released unless the response gets stored, so that a
+			// server error or a panic in next leaves the request retryable. The
+			// client may be gone by then, hence the context without cancel.
+			storeCtx := context.WithoutCancel(ctx)
+			stored := false
+			defer func() {
+				if stored {
+					return
+				}
+				err := idempotency.Release(storeCtx, rec)
+				if err != nil {
+					log.Error("Failed to release idempotency key", logger.F.Error(err))
+				}
+			}()
+
+			rw := &recordingResponseWriter{ResponseWriter: res}
+			next.ServeHTTP(rw, req)
+
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+			if rw.status >= http.StatusInternalServerError {
+				return
+			}
+
+			rec.StatusCode = rw.status
+			rec.ContentType = rw.Header().Get("Content-Type")
+			rec.Body = rw.body.Bytes()
+			err = idempotency.Complete(storeCtx, rec)
+			if err != nil {
+				log.Error("Failed to save idempotent response", logger.F.Error(err))
+				return
+			}
+			stored = true
+		})
+	}
+}
+
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(req.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(res http.ResponseWriter, rec *models.IdempotencyRecord) {
+	if rec.ContentType != "" {
+		res.Header().Set("Content-Type", rec.ContentType)
+	}
+	res.Header().Set(idempotentReplayedHeader, "true")
+	res.WriteHeader(rec.StatusCode)
+	res.Write(rec.Body)
+}
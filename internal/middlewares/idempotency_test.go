@@ -0,0 +1,166 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newIdempotencyTestHandler(next http.HandlerFunc) http.Handler {
+	service := services.NewIdempotencyService(memory.NewIdempotencyRepository(memory.New()), time.Hour)
+	return Idempotency(service)(next)
+}
+
+func idempotentRequest(ctx context.Context, key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/user/orders", strings.NewReader(body))
+	req.Header.Set(idempotencyKeyHeader, key)
+	return req.WithContext(services.ContextWithUser(ctx, &services.UserContext{ID: "user123"}))
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("повтор возвращает сохраненный ответ", func(t *testing.T) {
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("accepted"))
+		})
+
+		first := serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+		require.Equal(t, http.StatusAccepted, first.Code)
+		require.Empty(t, first.Header().Get(idempotentReplayedHeader))
+
+		second := serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+		require.Equal(t, http.StatusAccepted, second.Code)
+		require.Equal(t, "accepted", second.Body.String())
+		require.Equal(t, "text/plain", second.Header().Get("Content-Type"))
+		require.Equal(t, "true", second.Header().Get(idempotentReplayedHeader))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("ключ для другого запроса", func(t *testing.T) {
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		require.Equal(t, http.StatusAccepted,
+			serve(h, idempotentRequest(context.Background(), "key-1", "12345678903")).Code)
+		res := serve(h, idempotentRequest(context.Background(), "key-1", "2377225624"))
+		require.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+
+	t.Run("запрос с тем же ключом еще выполняется", func(t *testing.T) {
+		var h http.Handler
+		var inner *httptest.ResponseRecorder
+		h = newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			if inner == nil {
+				inner = serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+			}
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		res := serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+		require.Equal(t, http.StatusAccepted, res.Code)
+		require.Equal(t, http.StatusConflict, inner.Code)
+	})
+
+	t.Run("ответ сохраняется после отключения клиента", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			cancel()
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		serve(h, idempotentRequest(ctx, "key-1", "12345678903"))
+		res := serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+		require.Equal(t, http.StatusAccepted, res.Code)
+		require.Equal(t, "true", res.Header().Get(idempotentReplayedHeader))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("ключ освобождается после ошибки сервера", func(t *testing.T) {
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		require.Equal(t, http.StatusInternalServerError,
+			serve(h, idempotentRequest(context.Background(), "key-1", "12345678903")).Code)
+		require.Equal(t, http.StatusAccepted,
+			serve(h, idempotentRequest(context.Background(), "key-1", "12345678903")).Code)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("ключ освобождается после паники", func(t *testing.T) {
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				panic("handler failed")
+			}
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		require.Panics(t, func() { serve(h, idempotentRequest(context.Background(), "key-1", "12345678903")) })
+		res := serve(h, idempotentRequest(context.Background(), "key-1", "12345678903"))
+		require.Equal(t, http.StatusAccepted, res.Code)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("слишком большое тело запроса", func(t *testing.T) {
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		body := strings.Repeat("1", maxIdempotentBodyBytes+1)
+		res := serve(h, idempotentRequest(context.Background(), "key-1", body))
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+		require.Zero(t, calls)
+	})
+
+	t.Run("запрос без ключа не сохраняется", func(t *testing.T) {
+		calls := 0
+		h := newIdempotencyTestHandler(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		for i := 0; i < 2; i++ {
+			require.Equal(t, http.StatusAccepted,
+				serve(h, idempotentRequest(context.Background(), "", "12345678903")).Code)
+		}
+		require.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/metrics"
+	"github.com/go-chi/chi"
+)
+
+// Metrics records request counts and latency by chi route pattern, so that
+// paths with order numbers or user ids do not create a series per request.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := loggingResponseWriter{
+			ResponseWriter: w,
+			responseData:   &responseData{},
+		}
+
+		next.ServeHTTP(&lw, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := lw.responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BalanceAdjustment is a manual credit (positive amount) or debit (negative
+// amount) made by an administrator.
+type BalanceAdjustment struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Amount     Points    `json:"amount" db:"amount"`
+	Reason     string    `json:"reason" db:"reason"`
+	Ticket     string    `json:"ticket" db:"ticket"`
+	OperatorID string    `json:"operator_id" db:"operator_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+func NewBalanceAdjustment(userID string, amount Points, reason, ticket, operatorID string) *BalanceAdjustment {
+	return &BalanceAdjustment{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Amount:     amount,
+		Reason:     reason,
+		Ticket:     ticket,
+		OperatorID: operatorID,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditRoleGranted   AuditAction = "user.role_granted"
+	AuditRoleRevoked   AuditAction = "user.role_revoked"
+	AuditUserBlocked   AuditAction = "user.blocked"
+	AuditUserUnblocked AuditAction = "user.unblocked"
+	AuditUserUnlocked  AuditAction = "user.unlocked"
+	AuditOrderRequeued AuditAction = "order.requeued"
+	AuditBalanceAdjust AuditAction = "balance.adjusted"
+)
+
+const (
+	AuditTargetUser  = "user"
+	AuditTargetOrder = "order"
+)
+
+type AuditAction string
+
+type AuditDetails map[string]string
+
+type AuditEntry struct {
+	ID         string       `json:"id" db:"id"`
+	ActorID    string       `json:"actor_id" db:"actor_id"`
+	Action     AuditAction  `json:"action" db:"action"`
+	TargetType string       `json:"target_type" db:"target_type"`
+	TargetID   string       `json:"target_id" db:"target_id"`
+	Details    AuditDetails `json:"details,omitempty" db:"details"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
+
+func NewAuditEntry(actorID string, action AuditAction, targetType, targetID string, details AuditDetails) *AuditEntry {
+	return &AuditEntry{
+		ID:         uuid.New().String(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+func (d *AuditDetails) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+
+	switch src := value.(type) {
+	case []byte:
+		return json.Unmarshal(src, d)
+	case string:
+		return json.Unmarshal([]byte(src), d)
+	}
+	return errors.New("cannot scan into AuditDetails")
+}
+
+func (d AuditDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
@@ -1,7 +1,15 @@
 package models
 
 type Balance struct {
-	UserID    string  `json:"-"`
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	UserID    string `json:"-" db:"user_id"`
+	Current   Points `json:"current" db:"current"`
+	Withdrawn Points `json:"withdrawn" db:"withdrawn"`
+}
+
+type BalanceMismatch struct {
+	UserID            string `json:"user_id" db:"user_id"`
+	Current           Points `json:"current" db:"current"`
+	Withdrawn         Points `json:"withdrawn" db:"withdrawn"`
+	ExpectedCurrent   Points `json:"expected_current" db:"expected_current"`
+	ExpectedWithdrawn Points `json:"expected_withdrawn" db:"expected_withdrawn"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is a response stored for an Idempotency-Key.
+// StatusCode stays zero while the original request is still being processed,
+// LockedUntil bounds how long that request may hold the key.
+type IdempotencyRecord struct {
+	UserID      string     `db:"user_id"`
+	Key         string     `db:"key"`
+	RequestHash string     `db:"request_hash"`
+	StatusCode  int        `db:"status_code"`
+	ContentType string     `db:"content_type"`
+	Body        []byte     `db:"body"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LockedUntil *time.Time `db:"locked_until"`
+}
+
+func NewIdempotencyRecord(userID, key, requestHash string, lease time.Duration) *IdempotencyRecord {
+	now := time.Now().UTC()
+	lockedUntil := IdempotencyLeaseUntil(now, lease)
+	return &IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		LockedUntil: &lockedUntil,
+	}
+}
+
+// IdempotencyLeaseUntil is the end of a lease taken at now. LockedUntil also
+// identifies the lease holder, so it is cut to the microseconds the databases
+// keep and compares equal after a round trip.
+func IdempotencyLeaseUntil(now time.Time, lease time.Duration) time.Time {
+	return now.Add(lease).Truncate(time.Microsecond)
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// Locked reports whether the request that reserved the key may still be running.
+func (r *IdempotencyRecord) Locked(now time.Time) bool {
+	return r.LockedUntil != nil && r.LockedUntil.After(now)
+}
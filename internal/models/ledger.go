@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LedgerEntryAccrual    LedgerEntryKind = "accrual"
+	LedgerEntryWithdrawal LedgerEntryKind = "withdrawal"
+	LedgerEntryAdjustment LedgerEntryKind = "adjustment"
+)
+
+type LedgerEntryKind string
+
+type LedgerEntry struct {
+	ID           string          `json:"-" db:"id"`
+	UserID       string          `json:"-" db:"user_id"`
+	Kind         LedgerEntryKind `json:"kind" db:"kind"`
+	OrderNumber  string          `json:"order,omitempty" db:"order_number"`
+	Amount       Points          `json:"amount" db:"amount"`
+	BalanceAfter Points          `json:"balance" db:"balance_after"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+}
+
+func NewLedgerEntry(userID string, kind LedgerEntryKind, orderNumber string, amount Points) *LedgerEntry {
+	return &LedgerEntry{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Kind:        kind,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	LoginAttemptScopeLogin LoginAttemptScope = "login"
+	LoginAttemptScopeIP    LoginAttemptScope = "ip"
+)
+
+type LoginAttemptScope string
+
+// LoginAttempt counts recent failed logins for a login name or a client IP.
+type LoginAttempt struct {
+	Scope         LoginAttemptScope `json:"scope" db:"scope"`
+	Key           string            `json:"key" db:"key"`
+	Failures      int               `json:"failures" db:"failures"`
+	LastFailureAt time.Time         `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time        `json:"locked_until,omitempty" db:"locked_until"`
+}
+
+func (a *LoginAttempt) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"slices"
 	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
 )
 
 var (
@@ -12,22 +16,67 @@ var (
 	StatusProcessed  OrderStatus = "PROCESSED"
 )
 
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	StatusNew:        {StatusProcessing, StatusInvalid, StatusProcessed},
+	StatusProcessing: {StatusProcessing, StatusInvalid, StatusProcessed},
+}
+
 type OrderStatus string
 
+// ParseAccrualStatus maps a status reported by the accrual system to the order
+// status. REGISTERED means the accrual system accepted the order but has not
+// calculated it yet, which is PROCESSING from the user's point of view.
+func ParseAccrualStatus(status string) (OrderStatus, error) {
+	switch s := OrderStatus(status); s {
+	case StatusRegistered, StatusProcessing:
+		return StatusProcessing, nil
+	case StatusInvalid, StatusProcessed:
+		return s, nil
+	}
+	return "", fmt.Errorf("%w: %q", errs.ErrOrderStatusUnknown, status)
+}
+
+func (s OrderStatus) IsFinal() bool {
+	return s == StatusInvalid || s == StatusProcessed
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], next)
+}
+
+// PreviousStatuses returns every status the order may be in to move to next.
+func PreviousStatuses(next OrderStatus) []OrderStatus {
+	var statuses []OrderStatus
+	for from, to := range orderTransitions {
+		if slices.Contains(to, next) {
+			statuses = append(statuses, from)
+		}
+	}
+	slices.Sort(statuses)
+	return statuses
+}
+
 type Order struct {
-	Number     string      `json:"number" db:"number"`
-	UserID     string      `json:"-" db:"user_id"`
-	Status     OrderStatus `json:"status" db:"status"`
-	Accrual    *float64    `json:"accrual,omitempty" db:"accrual"`
-	UploadedAt time.Time   `json:"uploaded_at" db:"uploaded_at"`
+	Number        string      `json:"number" db:"number"`
+	UserID        string      `json:"-" db:"user_id"`
+	Status        OrderStatus `json:"status" db:"status"`
+	Accrual       *Points     `json:"accrual,omitempty" db:"accrual"`
+	UploadedAt    time.Time   `json:"uploaded_at" db:"uploaded_at"`
+	Attempts      int         `json:"-" db:"attempts"`
+	NextAttemptAt time.Time   `json:"-" db:"next_attempt_at"`
+	LockedUntil   *time.Time  `json:"-" db:"locked_until"`
+	NeedsReview   bool        `json:"-" db:"needs_review"`
+	Rejections    int         `json:"-" db:"rejections"`
 }
 
 func NewOrder(number, userID string) *Order {
+	now := time.Now().UTC()
 	return &Order{
-		Number:     number,
-		UserID:     userID,
-		Status:     StatusNew,
-		Accrual:    nil,
-		UploadedAt: time.Now().UTC(),
+		Number:        number,
+		UserID:        userID,
+		Status:        StatusNew,
+		Accrual:       nil,
+		UploadedAt:    now,
+		NextAttemptAt: now,
 	}
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccrualStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected OrderStatus
+		wantErr  bool
+	}{
+		{name: "REGISTERED", input: "REGISTERED", expected: StatusProcessing},
+		{name: "PROCESSING", input: "PROCESSING", expected: StatusProcessing},
+		{name: "INVALID", input: "INVALID", expected: StatusInvalid},
+		{name: "PROCESSED", input: "PROCESSED", expected: StatusProcessed},
+		{name: "NEW не приходит из системы расчёта", input: "NEW", wantErr: true},
+		{name: "неизвестный статус", input: "DONE", wantErr: true},
+		{name: "регистр важен", input: "processed", wantErr: true},
+		{name: "пустой статус", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := ParseAccrualStatus(tt.input)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errs.ErrOrderStatusUnknown)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, status)
+		})
+	}
+}
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	statuses := []OrderStatus{StatusNew, StatusRegistered, StatusProcessing, StatusInvalid, StatusProcessed}
+	allowed := map[OrderStatus][]OrderStatus{
+		StatusNew:        {StatusProcessing, StatusInvalid, StatusProcessed},
+		StatusProcessing: {StatusProcessing, StatusInvalid, StatusProcessed},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			expected := false
+			for _, v := range allowed[from] {
+				if v == to {
+					expected = true
+				}
+			}
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				require.Equal(t, expected, from.CanTransitionTo(to))
+			})
+		}
+	}
+}
+
+func TestOrderStatus_IsFinal(t *testing.T) {
+	require.False(t, StatusNew.IsFinal())
+	require.False(t, StatusProcessing.IsFinal())
+	require.True(t, StatusInvalid.IsFinal())
+	require.True(t, StatusProcessed.IsFinal())
+}
+
+func TestPreviousStatuses(t *testing.T) {
+	require.Equal(t, []OrderStatus{StatusNew, StatusProcessing}, PreviousStatuses(StatusProcessed))
+	require.Equal(t, []OrderStatus{StatusNew, StatusProcessing}, PreviousStatuses(StatusProcessing))
+	require.Empty(t, PreviousStatuses(StatusNew))
+	require.Empty(t, PreviousStatuses(StatusRegistered))
+}
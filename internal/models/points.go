@@ -0,0 +1,128 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const pointsScale = 100
+
+var ErrInvalidPoints = errors.New("invalid points amount")
+
+// Points is an exact amount of loyalty points kept in hundredths, so that
+// arithmetic and comparisons are free of float rounding.
+type Points int64
+
+// ParsePoints parses a decimal number such as "729.98" or "-3.5". Fractions,
+// exponents and more than two fractional digits are rejected rather than
+// rounded, so an amount is never silently changed.
+func ParsePoints(s string) (Points, error) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	neg := strings.HasPrefix(whole, "-")
+	whole = strings.TrimPrefix(whole, "-")
+	if !isDigits(whole) || hasFrac && (len(frac) > 2 || !isDigits(frac)) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPoints, s)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidPoints, s)
+	}
+	if neg {
+		v = -v
+	}
+	return Points(v), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the amount with exactly two fractional digits.
+func (p Points) String() string {
+	sign := ""
+	v := int64(p)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/pointsScale, v%pointsScale)
+}
+
+// Float64 returns the approximate amount for reporting, never use it in arithmetic.
+func (p Points) Float64() float64 {
+	return float64(p) / pointsScale
+}
+
+func (p Points) MarshalJSON() ([]byte, error) {
+	return []byte(trimZeroFraction(p.String())), nil
+}
+
+func (p *Points) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	v, err := ParsePoints(string(data))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+func (p *Points) Scan(value interface{}) error {
+	var (
+		v   Points
+		err error
+	)
+	switch src := value.(type) {
+	case nil:
+		v = 0
+	case []byte:
+		v, err = ParsePoints(string(src))
+	case string:
+		v, err = ParsePoints(src)
+	case int64:
+		v = Points(src * pointsScale)
+	case float64:
+		// SQLite hands DECIMAL columns and their sums over as floats.
+		v = Points(math.Round(src * pointsScale))
+	default:
+		return fmt.Errorf("cannot scan %T into Points", value)
+	}
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+func (p Points) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+func trimZeroFraction(s string) string {
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
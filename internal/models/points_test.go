@@ -0,0 +1,145 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Points
+		wantErr  bool
+	}{
+		{name: "целое число", input: "500", expected: 50000},
+		{name: "две цифры после точки", input: "729.98", expected: 72998},
+		{name: "одна цифра после точки", input: "0.1", expected: 10},
+		{name: "отрицательное число", input: "-3.5", expected: -350},
+		{name: "ноль с дробной частью", input: "0.00", expected: 0},
+		{name: "предел int64", input: "92233720368547758.07", expected: 9223372036854775807},
+		{name: "больше int64", input: "92233720368547758.08", wantErr: true},
+		{name: "три цифры после точки", input: "1.005", wantErr: true},
+		{name: "меньше сотой", input: "0.004", wantErr: true},
+		{name: "экспонента", input: "1e2", wantErr: true},
+		{name: "дробь", input: "1/3", wantErr: true},
+		{name: "точка без цифр", input: "1.", wantErr: true},
+		{name: "без целой части", input: ".5", wantErr: true},
+		{name: "знак плюс", input: "+1", wantErr: true},
+		{name: "пробелы", input: " 1", wantErr: true},
+		{name: "не число", input: "abc", wantErr: true},
+		{name: "пустая строка", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePoints(tt.input)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPoints)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, p)
+		})
+	}
+}
+
+func TestPoints_Arithmetic(t *testing.T) {
+	a, err := ParsePoints("0.1")
+	require.NoError(t, err)
+	b, err := ParsePoints("0.2")
+	require.NoError(t, err)
+	c, err := ParsePoints("0.3")
+	require.NoError(t, err)
+
+	require.Equal(t, c, a+b)
+	require.False(t, c < a+b)
+	require.Equal(t, "0.30", (a + b).String())
+}
+
+func TestPoints_JSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   Points
+		expected string
+	}{
+		{name: "целое", points: 50000, expected: "500"},
+		{name: "десятые", points: 10050, expected: "100.5"},
+		{name: "сотые", points: 72998, expected: "729.98"},
+		{name: "ноль", points: 0, expected: "0"},
+		{name: "отрицательное", points: -1005, expected: "-10.05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.points)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(data))
+
+			var decoded Points
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			require.Equal(t, tt.points, decoded)
+		})
+	}
+
+	t.Run("null", func(t *testing.T) {
+		var payload struct {
+			Sum     Points  `json:"sum"`
+			Accrual *Points `json:"accrual"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{"sum": 751.1, "accrual": null}`), &payload))
+		require.Equal(t, Points(75110), payload.Sum)
+		require.Nil(t, payload.Accrual)
+	})
+
+	t.Run("строка вместо числа", func(t *testing.T) {
+		var p Points
+		require.ErrorIs(t, json.Unmarshal([]byte(`"751.1"`), &p), ErrInvalidPoints)
+	})
+
+	t.Run("экспонента", func(t *testing.T) {
+		var p Points
+		require.ErrorIs(t, json.Unmarshal([]byte(`1e2`), &p), ErrInvalidPoints)
+	})
+
+	t.Run("невалидное значение", func(t *testing.T) {
+		var p Points
+		require.Error(t, json.Unmarshal([]byte(`true`), &p))
+	})
+}
+
+func TestPoints_SQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected Points
+	}{
+		{name: "decimal из postgres", value: []byte("100.50"), expected: 10050},
+		{name: "строка", value: "0.30", expected: 30},
+		{name: "float", value: 100.5, expected: 10050},
+		{name: "float с погрешностью суммы", value: 0.30000000000000004, expected: 30},
+		{name: "int", value: int64(7), expected: 700},
+		{name: "null", value: nil, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Points
+			require.NoError(t, p.Scan(tt.value))
+			require.Equal(t, tt.expected, p)
+		})
+	}
+
+	t.Run("неподдерживаемый тип", func(t *testing.T) {
+		var p Points
+		require.Error(t, p.Scan(true))
+	})
+
+	t.Run("value", func(t *testing.T) {
+		v, err := Points(10050).Value()
+		require.NoError(t, err)
+		require.Equal(t, "100.50", v)
+	})
+}
@@ -15,18 +15,23 @@ type Role string
 
 type Roles []Role
 
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
 func (r *Roles) Scan(value interface{}) error {
 	if value == nil {
 		*r = nil
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("cannot scan into Roles")
+	switch src := value.(type) {
+	case []byte:
+		return json.Unmarshal(src, r)
+	case string:
+		return json.Unmarshal([]byte(src), r)
 	}
-
-	return json.Unmarshal(bytes, r)
+	return errors.New("cannot scan into Roles")
 }
 
 func (r Roles) Value() (driver.Value, error) {
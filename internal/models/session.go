@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID               string     `json:"id" db:"id"`
+	UserID           string     `json:"-" db:"user_id"`
+	RefreshTokenHash string     `json:"-" db:"refresh_token_hash"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt        time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+func NewSession(userID, refreshTokenHash string, ttl time.Duration) *Session {
+	now := time.Now().UTC()
+	return &Session{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		RefreshTokenHash: refreshTokenHash,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(ttl),
+	}
+}
+
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -13,6 +13,11 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	Roles        Roles      `json:"roles" db:"roles"`
 	LastLoginAt  *time.Time `json:"last_login_at" db:"last_login_at"`
+	BlockedAt    *time.Time `json:"blocked_at,omitempty" db:"blocked_at"`
+
+	TOTPSecret    *string    `json:"-" db:"totp_secret"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty" db:"totp_enabled_at"`
+	TOTPLastStep  *int64     `json:"-" db:"totp_last_step"`
 }
 
 func NewUser(login, passwordHash string) *User {
@@ -24,3 +29,11 @@ func NewUser(login, passwordHash string) *User {
 		Roles:        []Role{RoleUser},
 	}
 }
+
+func (u *User) Blocked() bool {
+	return u.BlockedAt != nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != nil
+}
@@ -10,11 +10,11 @@ type Withdrawal struct {
 	ID          string    `json:"-" db:"id"`
 	UserID      string    `json:"-" db:"user_id"`
 	OrderNumber string    `json:"order" db:"order_number"`
-	Sum         float64   `json:"sum" db:"sum"`
+	Sum         Points    `json:"sum" db:"sum"`
 	ProcessedAt time.Time `json:"processed_at" db:"processed_at"`
 }
 
-func NewWithdrawal(userID, orderNumber string, sum float64) *Withdrawal {
+func NewWithdrawal(userID, orderNumber string, sum Points) *Withdrawal {
 	return &Withdrawal{
 		ID:          uuid.New().String(),
 		UserID:      userID,
@@ -3,23 +3,44 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Soliard/gophermart/internal/dto"
 	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/metrics"
 	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/tracing"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	accrualLease       = 5 * time.Minute
+	accrualBackoffBase = 10 * time.Second
+	accrualBackoffMax  = 30 * time.Minute
+	// accrualMaxRejections is how many times in a row a response may be
+	// rejected before the order is held for manual review.
+	accrualMaxRejections = 3
 )
 
 type AccrualUpdater interface {
-	GetOrdersToAccrualUpdate(ctx context.Context) ([]*models.Order, error)
+	ClaimOrdersToAccrualUpdate(ctx context.Context, limit int, lease time.Duration) ([]*models.Order, error)
+	ScheduleAccrualRetry(ctx context.Context, numberOrder string, delay time.Duration) error
+	ScheduleAccrualRejection(ctx context.Context, numberOrder string, delay time.Duration) error
+	HoldAccrualForReview(ctx context.Context, numberOrder string) error
 	UpdateStatusAndAccural(
 		ctx context.Context, numberOrder string,
-		status models.OrderStatus, accrual *float64) error
+		status models.OrderStatus, accrual *models.Points) error
 }
 
 type RetryConfig struct {
@@ -27,64 +48,143 @@ type RetryConfig struct {
 	RetryDelay time.Duration
 }
 
+// AccrualConfig tunes the polling of the accrual system. Workers is the
+// number of concurrent requests and BatchSize the max orders per poll.
+type AccrualConfig struct {
+	Workers   int
+	BatchSize int
+	Timeout   time.Duration
+	Retry     RetryConfig
+}
+
 type accrualService struct {
-	updater  AccrualUpdater
-	client   *resty.Client
-	baseURL  string
-	retryCfg RetryConfig
+	updater   AccrualUpdater
+	client    *resty.Client
+	baseURL   string
+	retryCfg  RetryConfig
+	workers   int
+	batchSize int
+	limiter   *rateLimiter
 }
 
-func NewAccrualService(orders AccrualUpdater, accrualURL string) *accrualService {
+func NewAccrualService(orders AccrualUpdater, accrualURL string, cfg AccrualConfig) *accrualService {
 	if !strings.HasPrefix(accrualURL, "http://") && !strings.HasPrefix(accrualURL, "https://") {
 		accrualURL = "http://" + accrualURL
 	}
 
-	retryCfg := RetryConfig{
-		MaxRetries: 3,
-		RetryDelay: time.Second * 60,
-	}
-
 	return &accrualService{
-		updater:  orders,
-		client:   resty.New(),
-		baseURL:  accrualURL,
-		retryCfg: retryCfg,
+		updater: orders,
+		client: resty.New().
+			SetTransport(otelhttp.NewTransport(http.DefaultTransport)).
+			SetTimeout(cfg.Timeout),
+		baseURL:   accrualURL,
+		retryCfg:  cfg.Retry,
+		workers:   max(cfg.Workers, 1),
+		batchSize: cfg.BatchSize,
+		limiter:   newRateLimiter(),
 	}
 }
 
 func (s *accrualService) UpdateOrders(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "accrualService.UpdateOrders")
+	defer span.End()
+
 	log := logger.FromContext(ctx)
-	orders, err := s.updater.GetOrdersToAccrualUpdate(ctx)
+	orders, err := s.updater.ClaimOrdersToAccrualUpdate(ctx, s.batchSize, accrualLease)
 	if err != nil {
 		return err
 	}
+	metrics.AccrualOrdersPolled.Add(float64(len(orders)))
+	span.SetAttributes(attribute.Int("orders", len(orders)))
+
+	jobs := make(chan *models.Order)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for order := range jobs {
+				s.processOrder(ctx, log, order)
+			}
+		}()
+	}
+
+send:
 	for _, v := range orders {
-		err := s.updateOrder(ctx, v.Number)
-		if err != nil {
-			log.Error("Failed to update order", logger.F.Any("order", v), logger.F.Error(err))
+		select {
+		case jobs <- v:
+		case <-ctx.Done():
+			break send
 		}
 	}
-	return nil
+	close(jobs)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (s *accrualService) processOrder(ctx context.Context, log logger.Logger, order *models.Order) {
+	ctx, span := tracing.Start(ctx, "accrualService.processOrder", trace.WithAttributes(attribute.String("order", order.Number)))
+	err := s.updateOrder(ctx, order)
+	tracing.End(span, err)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	schedule := s.updater.ScheduleAccrualRetry
+	switch {
+	case errors.Is(err, errs.ErrOrderNotRegisteredInAccrual):
+		log.Debug("Order is not registered in accrual system yet", logger.F.String("order", order.Number))
+	case isAccrualRejection(err):
+		metrics.AccrualErrors.Inc()
+		if order.Rejections+1 >= accrualMaxRejections {
+			log.Error("Accrual response rejected, order is held for manual review",
+				logger.F.Any("order", order), logger.F.Error(err))
+			err = s.updater.HoldAccrualForReview(ctx, order.Number)
+			if err != nil {
+				log.Error("Failed to hold order for review", logger.F.Any("order", order), logger.F.Error(err))
+			}
+			return
+		}
+		log.Error("Accrual response rejected", logger.F.Any("order", order), logger.F.Error(err))
+		schedule = s.updater.ScheduleAccrualRejection
+	default:
+		metrics.AccrualErrors.Inc()
+		log.Error("Failed to update order", logger.F.Any("order", order), logger.F.Error(err))
+	}
+
+	delay := retryBackoff(order.Attempts)
+	err = schedule(ctx, order.Number, delay)
+	if err != nil {
+		log.Error("Failed to schedule order accrual retry", logger.F.Any("order", order), logger.F.Error(err))
+	}
 }
 
-func (s *accrualService) updateOrder(ctx context.Context, number string) error {
-	fullURL, err := url.JoinPath(s.baseURL, "api", "orders", number)
+func (s *accrualService) updateOrder(ctx context.Context, order *models.Order) error {
+	fullURL, err := url.JoinPath(s.baseURL, "api", "orders", order.Number)
 	if err != nil {
 		return err
 	}
 
 	for attempt := 0; attempt < s.retryCfg.MaxRetries; attempt++ {
-		resp, err := s.client.R().Get(fullURL)
+		err := s.limiter.wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.client.R().SetContext(ctx).Get(fullURL)
 		if err != nil {
 			return err
 		}
 		status := resp.StatusCode()
+		metrics.AccrualResponses.WithLabelValues(strconv.Itoa(status)).Inc()
 		switch status {
 		case http.StatusOK:
 		case http.StatusNoContent:
-			return nil
+			return errs.ErrOrderNotRegisteredInAccrual
 		case http.StatusTooManyRequests:
-			time.Sleep(s.retryCfg.RetryDelay)
+			s.limiter.setRate(parseRateLimit(resp.String()))
+			s.limiter.pause(parseRetryAfter(resp.Header().Get("Retry-After"), s.retryCfg.RetryDelay))
 			continue
 		default:
 			return errs.ErrUnexpectedStatusAccrualService
@@ -95,15 +195,58 @@ func (s *accrualService) updateOrder(ctx context.Context, number string) error {
 		if err != nil {
 			return err
 		}
-		return s.updateStatusAndAccural(ctx, recievedOrder.Order, models.OrderStatus(recievedOrder.Status), recievedOrder.Accrual)
+		return s.updateStatusAndAccural(ctx, order, recievedOrder.Status, recievedOrder.Accrual)
 	}
 
-	return nil
+	return errs.ErrAccrualRateLimited
 }
 
 func (s *accrualService) updateStatusAndAccural(
-	ctx context.Context, number string,
-	status models.OrderStatus, accural *float64) error {
+	ctx context.Context, order *models.Order,
+	accrualStatus string, accural *models.Points) error {
+
+	status, err := models.ParseAccrualStatus(accrualStatus)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", errs.ErrOrderStatusTransition, order.Status, status)
+	}
+	if status != models.StatusProcessed {
+		accural = nil
+	}
 
-	return s.updater.UpdateStatusAndAccural(ctx, number, status, accural)
+	return s.updater.UpdateStatusAndAccural(ctx, order.Number, status, accural)
+}
+
+// isAccrualRejection reports whether the accrual system answered with a status
+// the order cannot take, so polling again is unlikely to help.
+func isAccrualRejection(err error) bool {
+	return errors.Is(err, errs.ErrOrderStatusUnknown) || errors.Is(err, errs.ErrOrderStatusTransition)
+}
+
+// Ping asks the accrual system about an order that is never uploaded. Only a
+// 2xx answer, normally 204, shows that the orders API is served.
+func (s *accrualService) Ping(ctx context.Context) error {
+	fullURL, err := url.JoinPath(s.baseURL, "api", "orders", "0")
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.R().SetContext(ctx).Get(fullURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrAccrualUnreachable, err)
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("%w: status %d", errs.ErrAccrualUnreachable, resp.StatusCode())
+	}
+	return nil
+}
+
+// retryBackoff doubles the delay after every failed attempt up to accrualBackoffMax.
+func retryBackoff(attempts int) time.Duration {
+	delay := accrualBackoffBase
+	for i := 0; i < attempts && delay < accrualBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, accrualBackoffMax)
 }
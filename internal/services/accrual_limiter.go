@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var rateLimitHint = regexp.MustCompile(`(\d+)\s+requests?\s+per\s+minute`)
+
+// rateLimiter is shared by all accrual workers. It holds a global pause set
+// from Retry-After and spaces requests according to the advertised rate.
+type rateLimiter struct {
+	mu       sync.Mutex
+	until    time.Time
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(l.until) {
+		l.until = until
+	}
+}
+
+func (l *rateLimiter) setRate(perMinute int) {
+	if perMinute <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = time.Minute / time.Duration(perMinute)
+}
+
+// wait blocks until the caller may send the next request or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		at := l.until
+		if l.next.After(at) {
+			at = l.next
+		}
+		if !at.After(now) {
+			if l.interval > 0 {
+				l.next = now.Add(l.interval)
+			}
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+		l.mu.Unlock()
+
+		err := sleepContext(ctx, at.Sub(now))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter supports both delay-seconds and HTTP-date forms of the header.
+func parseRetryAfter(header string, fallback time.Duration) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return fallback
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return fallback
+}
+
+// parseRateLimit extracts N from "No more than N requests per minute allowed".
+func parseRateLimit(body string) int {
+	m := rateLimitHint.FindStringSubmatch(body)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
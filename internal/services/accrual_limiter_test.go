@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	fallback := time.Minute
+	tests := []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{
+		{name: "секунды", header: "60", expected: 60 * time.Second},
+		{name: "ноль", header: "0", expected: 0},
+		{name: "пустой заголовок", header: "", expected: fallback},
+		{name: "мусор", header: "soon", expected: fallback},
+		{name: "отрицательное значение", header: "-5", expected: fallback},
+		{name: "дата в прошлом", header: "Wed, 21 Oct 2015 07:28:00 GMT", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseRetryAfter(tt.header, fallback))
+		})
+	}
+
+	t.Run("дата в будущем", func(t *testing.T) {
+		header := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+		d := parseRetryAfter(header, fallback)
+		require.Greater(t, d, 25*time.Second)
+		require.LessOrEqual(t, d, 30*time.Second)
+	})
+}
+
+func TestParseRateLimit(t *testing.T) {
+	require.Equal(t, 60, parseRateLimit("No more than 60 requests per minute allowed"))
+	require.Equal(t, 1, parseRateLimit("No more than 1 request per minute allowed"))
+	require.Equal(t, 0, parseRateLimit("Too Many Requests"))
+	require.Equal(t, 0, parseRateLimit(""))
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("равномерное распределение запросов", func(t *testing.T) {
+		l := newRateLimiter()
+		l.setRate(1200)
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			require.NoError(t, l.wait(context.Background()))
+		}
+		require.GreaterOrEqual(t, time.Since(start), 4*50*time.Millisecond)
+	})
+
+	t.Run("глобальная пауза", func(t *testing.T) {
+		l := newRateLimiter()
+		l.pause(100 * time.Millisecond)
+		l.pause(10 * time.Millisecond)
+
+		start := time.Now()
+		require.NoError(t, l.wait(context.Background()))
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("отмена контекста во время паузы", func(t *testing.T) {
+		l := newRateLimiter()
+		l.pause(time.Hour)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		require.ErrorIs(t, l.wait(ctx), context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
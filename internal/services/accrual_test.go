@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAccrualUpdater struct {
+	mock.Mock
+}
+
+func (m *mockAccrualUpdater) ClaimOrdersToAccrualUpdate(
+	ctx context.Context, limit int, lease time.Duration) ([]*models.Order, error) {
+	args := m.Called(ctx, limit, lease)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockAccrualUpdater) ScheduleAccrualRetry(ctx context.Context, numberOrder string, delay time.Duration) error {
+	args := m.Called(ctx, numberOrder, delay)
+	return args.Error(0)
+}
+
+func (m *mockAccrualUpdater) ScheduleAccrualRejection(ctx context.Context, numberOrder string, delay time.Duration) error {
+	args := m.Called(ctx, numberOrder, delay)
+	return args.Error(0)
+}
+
+func (m *mockAccrualUpdater) HoldAccrualForReview(ctx context.Context, numberOrder string) error {
+	args := m.Called(ctx, numberOrder)
+	return args.Error(0)
+}
+
+func (m *mockAccrualUpdater) UpdateStatusAndAccural(
+	ctx context.Context, numberOrder string,
+	status models.OrderStatus, accrual *models.Points) error {
+	args := m.Called(ctx, numberOrder, status, accrual)
+	return args.Error(0)
+}
+
+func testAccrualConfig(workers int) AccrualConfig {
+	return AccrualConfig{
+		Workers:   workers,
+		BatchSize: 100,
+		Timeout:   10 * time.Second,
+		Retry:     RetryConfig{MaxRetries: 3, RetryDelay: 60 * time.Second},
+	}
+}
+
+func testOrders(n int) []*models.Order {
+	orders := make([]*models.Order, 0, n)
+	for i := 0; i < n; i++ {
+		orders = append(orders, models.NewOrder(fmt.Sprintf("%d", 1000+i), "user123"))
+	}
+	return orders
+}
+
+func orderNumberFromPath(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func TestAccrualService_UpdateOrders_WorkerPool(t *testing.T) {
+	const (
+		ordersCount = 20
+		workers     = 4
+	)
+
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"order": %q, "status": "PROCESSED", "accrual": 500}`, orderNumberFromPath(r.URL.Path))
+	}))
+	defer server.Close()
+
+	updater := new(mockAccrualUpdater)
+	updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).Return(testOrders(ordersCount), nil)
+	updater.On("UpdateStatusAndAccural", mock.Anything, mock.Anything, models.StatusProcessed, mock.Anything).
+		Return(nil).Times(ordersCount)
+
+	service := NewAccrualService(updater, server.URL, testAccrualConfig(workers))
+	err := service.UpdateOrders(context.Background())
+	require.NoError(t, err)
+
+	require.LessOrEqual(t, maxInFlight.Load(), int32(workers))
+	require.Greater(t, maxInFlight.Load(), int32(1))
+	updater.AssertExpectations(t)
+}
+
+func TestAccrualService_UpdateOrders_SharedBackoff(t *testing.T) {
+	const (
+		retryDelay = 200 * time.Millisecond
+		latency    = 40 * time.Millisecond
+	)
+
+	var (
+		mu        sync.Mutex
+		rejected  time.Time
+		arrivals  []time.Time
+		requested atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		if requested.Add(1) == 1 {
+			mu.Lock()
+			rejected = now
+			mu.Unlock()
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		mu.Lock()
+		arrivals = append(arrivals, now)
+		mu.Unlock()
+
+		time.Sleep(latency)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"order": %q, "status": "PROCESSING"}`, orderNumberFromPath(r.URL.Path))
+	}))
+	defer server.Close()
+
+	updater := new(mockAccrualUpdater)
+	updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).Return(testOrders(6), nil)
+	updater.On("UpdateStatusAndAccural", mock.Anything, mock.Anything, models.StatusProcessing, mock.Anything).
+		Return(nil).Times(6)
+
+	service := NewAccrualService(updater, server.URL, testAccrualConfig(3))
+	service.retryCfg.RetryDelay = retryDelay
+	err := service.UpdateOrders(context.Background())
+	require.NoError(t, err)
+	updater.AssertExpectations(t)
+
+	for _, arrival := range arrivals {
+		sinceRejected := arrival.Sub(rejected)
+		if sinceRejected <= latency/2 {
+			continue
+		}
+		require.GreaterOrEqual(t, sinceRejected, retryDelay-10*time.Millisecond,
+			"request sent while accrual system asked to pause")
+	}
+}
+
+func TestAccrualService_UpdateOrders_RetryAfter(t *testing.T) {
+	var (
+		requested atomic.Int32
+		rejected  time.Time
+		retried   time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requested.Add(1) == 1 {
+			rejected = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, "No more than 600 requests per minute allowed")
+			return
+		}
+		retried = time.Now()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"order": %q, "status": "INVALID"}`, orderNumberFromPath(r.URL.Path))
+	}))
+	defer server.Close()
+
+	updater := new(mockAccrualUpdater)
+	updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).Return(testOrders(1), nil)
+	updater.On("UpdateStatusAndAccural", mock.Anything, "1000", models.StatusInvalid, mock.Anything).Return(nil)
+
+	service := NewAccrualService(updater, server.URL, testAccrualConfig(1))
+	err := service.UpdateOrders(context.Background())
+	require.NoError(t, err)
+	updater.AssertExpectations(t)
+
+	require.GreaterOrEqual(t, retried.Sub(rejected), 900*time.Millisecond)
+	require.Less(t, retried.Sub(rejected), service.retryCfg.RetryDelay)
+	require.Equal(t, 100*time.Millisecond, service.limiter.interval)
+}
+
+func TestAccrualService_UpdateOrders_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	updater := new(mockAccrualUpdater)
+	updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).Return(testOrders(10), nil)
+
+	service := NewAccrualService(updater, server.URL, testAccrualConfig(2))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := service.UpdateOrders(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	updater.AssertNotCalled(t, "UpdateStatusAndAccural", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	updater.AssertNotCalled(t, "ScheduleAccrualRetry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccrualService_UpdateOrders_ScheduleRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		attempts      int
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "заказ не зарегистрирован в системе расчёта",
+			status:        http.StatusNoContent,
+			attempts:      0,
+			expectedDelay: 10 * time.Second,
+		},
+		{
+			name:          "ошибка системы расчёта после нескольких попыток",
+			status:        http.StatusInternalServerError,
+			attempts:      2,
+			expectedDelay: 40 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			order := models.NewOrder("79927398713", "user123")
+			order.Attempts = tt.attempts
+
+			updater := new(mockAccrualUpdater)
+			updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).
+				Return([]*models.Order{order}, nil)
+			updater.On("ScheduleAccrualRetry", mock.Anything, "79927398713", tt.expectedDelay).Return(nil)
+
+			service := NewAccrualService(updater, server.URL, testAccrualConfig(1))
+			err := service.UpdateOrders(context.Background())
+			require.NoError(t, err)
+			updater.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAccrualService_UpdateOrders_StatusMachine(t *testing.T) {
+	accrual := models.Points(50000)
+	tests := []struct {
+		name            string
+		current         models.OrderStatus
+		response        string
+		expectedStatus  models.OrderStatus
+		expectedAccrual *models.Points
+		rejected        bool
+	}{
+		{
+			name:           "REGISTERED становится PROCESSING",
+			current:        models.StatusNew,
+			response:       `{"order": "79927398713", "status": "REGISTERED"}`,
+			expectedStatus: models.StatusProcessing,
+		},
+		{
+			name:            "начисление для PROCESSED",
+			current:         models.StatusProcessing,
+			response:        `{"order": "79927398713", "status": "PROCESSED", "accrual": 500}`,
+			expectedStatus:  models.StatusProcessed,
+			expectedAccrual: &accrual,
+		},
+		{
+			name:           "начисление игнорируется для PROCESSING",
+			current:        models.StatusNew,
+			response:       `{"order": "79927398713", "status": "PROCESSING", "accrual": 500}`,
+			expectedStatus: models.StatusProcessing,
+		},
+		{
+			name:     "неизвестный статус",
+			current:  models.StatusNew,
+			response: `{"order": "79927398713", "status": "DONE"}`,
+			rejected: true,
+		},
+		{
+			name:     "обратный переход из финального статуса",
+			current:  models.StatusProcessed,
+			response: `{"order": "79927398713", "status": "PROCESSING"}`,
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			order := models.NewOrder("79927398713", "user123")
+			order.Status = tt.current
+
+			updater := new(mockAccrualUpdater)
+			updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).
+				Return([]*models.Order{order}, nil)
+			if tt.rejected {
+				updater.On("ScheduleAccrualRejection", mock.Anything, "79927398713", mock.Anything).Return(nil)
+			} else {
+				updater.On("UpdateStatusAndAccural", mock.Anything, "79927398713", tt.expectedStatus, tt.expectedAccrual).
+					Return(nil)
+			}
+
+			service := NewAccrualService(updater, server.URL, testAccrualConfig(1))
+			err := service.UpdateOrders(context.Background())
+			require.NoError(t, err)
+			updater.AssertExpectations(t)
+			if tt.rejected {
+				updater.AssertNotCalled(t, "UpdateStatusAndAccural", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAccrualService_UpdateOrders_HoldForReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		rejections int
+		held       bool
+	}{
+		{
+			name: "первый отказ откладывает повтор",
+		},
+		{
+			name:       "после лимита отказов заказ уходит на ручную проверку",
+			attempts:   accrualMaxRejections - 1,
+			rejections: accrualMaxRejections - 1,
+			held:       true,
+		},
+		{
+			name:     "временные ошибки не считаются отказами",
+			attempts: accrualMaxRejections - 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"order": "79927398713", "status": "DONE"}`)
+			}))
+			defer server.Close()
+
+			order := models.NewOrder("79927398713", "user123")
+			order.Attempts = tt.attempts
+			order.Rejections = tt.rejections
+
+			updater := new(mockAccrualUpdater)
+			updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).
+				Return([]*models.Order{order}, nil)
+			if tt.held {
+				updater.On("HoldAccrualForReview", mock.Anything, "79927398713").Return(nil)
+			} else {
+				updater.On("ScheduleAccrualRejection", mock.Anything, "79927398713", retryBackoff(tt.attempts)).
+					Return(nil)
+			}
+
+			service := NewAccrualService(updater, server.URL, testAccrualConfig(1))
+			err := service.UpdateOrders(context.Background())
+			require.NoError(t, err)
+			updater.AssertExpectations(t)
+			updater.AssertNotCalled(t, "ScheduleAccrualRetry", mock.Anything, mock.Anything, mock.Anything)
+			if tt.held {
+				updater.AssertNotCalled(t, "ScheduleAccrualRejection", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				updater.AssertNotCalled(t, "HoldAccrualForReview", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAccrualService_UpdateOrders_RejectionsInRow(t *testing.T) {
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+		func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"order": "79927398713", "status": "DONE"}`)
+		},
+	}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responses[polls](w)
+		polls++
+	}))
+	defer server.Close()
+
+	order := models.NewOrder("79927398713", "user123")
+	updater := new(mockAccrualUpdater)
+	updater.On("ClaimOrdersToAccrualUpdate", mock.Anything, testAccrualConfig(1).BatchSize, accrualLease).
+		Return([]*models.Order{order}, nil)
+	updater.On("ScheduleAccrualRetry", mock.Anything, "79927398713", mock.Anything).
+		Run(func(mock.Arguments) {
+			order.Attempts++
+			order.Rejections = 0
+		}).Return(nil)
+	updater.On("ScheduleAccrualRejection", mock.Anything, "79927398713", mock.Anything).
+		Run(func(mock.Arguments) {
+			order.Attempts++
+			order.Rejections++
+		}).Return(nil)
+
+	service := NewAccrualService(updater, server.URL, testAccrualConfig(1))
+	for range responses {
+		require.NoError(t, service.UpdateOrders(context.Background()))
+	}
+
+	require.Equal(t, len(responses), polls)
+	require.Equal(t, 3, order.Attempts)
+	require.Equal(t, 1, order.Rejections)
+	updater.AssertNotCalled(t, "HoldAccrualForReview", mock.Anything, mock.Anything)
+}
+
+func TestAccrualService_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		healthy bool
+	}{
+		{name: "200", status: http.StatusOK, healthy: true},
+		{name: "204", status: http.StatusNoContent, healthy: true},
+		{name: "404", status: http.StatusNotFound},
+		{name: "503", status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/orders/0", r.URL.Path)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			service := NewAccrualService(new(mockAccrualUpdater), server.URL, testAccrualConfig(1))
+			err := service.Ping(context.Background())
+			if tt.healthy {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, errs.ErrAccrualUnreachable)
+			}
+		})
+	}
+
+	t.Run("сервер недоступен", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		service := NewAccrualService(new(mockAccrualUpdater), server.URL, testAccrualConfig(1))
+		require.ErrorIs(t, service.Ping(context.Background()), errs.ErrAccrualUnreachable)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 0, expected: 10 * time.Second},
+		{attempts: 1, expected: 20 * time.Second},
+		{attempts: 5, expected: 320 * time.Second},
+		{attempts: 8, expected: 30 * time.Minute},
+		{attempts: 1000, expected: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("попытка %d", tt.attempts), func(t *testing.T) {
+			require.Equal(t, tt.expected, retryBackoff(tt.attempts))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type AdminStore interface {
+	SearchUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error)
+	UpdateRoles(ctx context.Context, userID string, roles models.Roles, entry *models.AuditEntry) error
+	SetBlocked(ctx context.Context, userID string, blockedAt *time.Time, entry *models.AuditEntry) error
+	UnlockLogin(ctx context.Context, login string, entry *models.AuditEntry) error
+	RequeueOrder(ctx context.Context, numberOrder string, entry *models.AuditEntry) error
+	AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment, entry *models.AuditEntry) error
+	GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error)
+	GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error)
+}
+
+type adminService struct {
+	store       AdminStore
+	users       UserGetter
+	orders      OrderCreator
+	withdrawals Withdrawer
+	sessions    SessionServiceInterface
+}
+
+func NewAdminService(
+	store AdminStore,
+	users UserGetter,
+	orders OrderCreator,
+	withdrawals Withdrawer,
+	sessions SessionServiceInterface) *adminService {
+
+	return &adminService{
+		store:       store,
+		users:       users,
+		orders:      orders,
+		withdrawals: withdrawals,
+		sessions:    sessions,
+	}
+}
+
+func (s *adminService) ListUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error) {
+	return s.store.SearchUsers(ctx, login, limit, offset)
+}
+
+func (s *adminService) GetUserOrders(ctx context.Context, userID string) ([]*models.Order, error) {
+	_, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.orders.GetUserOrders(ctx, userID)
+}
+
+func (s *adminService) GetUserWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
+	_, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withdrawals.GetWithdrawals(ctx, userID)
+}
+
+func (s *adminService) GrantRole(ctx context.Context, actorID, userID string, role models.Role) error {
+	if !role.Valid() {
+		return errs.ErrRoleUnknown
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(u.Roles, role) {
+		return nil
+	}
+
+	roles := append(slices.Clone(u.Roles), role)
+	entry := models.NewAuditEntry(actorID, models.AuditRoleGranted, models.AuditTargetUser, userID,
+		models.AuditDetails{"role": string(role)})
+	return s.store.UpdateRoles(ctx, userID, roles, entry)
+}
+
+// RevokeRole also ends all sessions of the user, otherwise already issued
+// access tokens would keep the revoked role until they expire.
+func (s *adminService) RevokeRole(ctx context.Context, actorID, userID string, role models.Role) error {
+	if !role.Valid() {
+		return errs.ErrRoleUnknown
+	}
+	if actorID == userID && role == models.RoleAdmin {
+		return errs.ErrAdminSelfAction
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(u.Roles, role) {
+		return nil
+	}
+
+	roles := slices.DeleteFunc(slices.Clone(u.Roles), func(r models.Role) bool { return r == role })
+	entry := models.NewAuditEntry(actorID, models.AuditRoleRevoked, models.AuditTargetUser, userID,
+		models.AuditDetails{"role": string(role)})
+	err = s.store.UpdateRoles(ctx, userID, roles, entry)
+	if err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(ctx, userID)
+}
+
+func (s *adminService) BlockUser(ctx context.Context, actorID, userID, reason string) error {
+	if actorID == userID {
+		return errs.ErrAdminSelfAction
+	}
+	now := time.Now().UTC()
+	entry := models.NewAuditEntry(actorID, models.AuditUserBlocked, models.AuditTargetUser, userID,
+		models.AuditDetails{"reason": reason})
+	return s.store.SetBlocked(ctx, userID, &now, entry)
+}
+
+func (s *adminService) UnblockUser(ctx context.Context, actorID, userID string) error {
+	entry := models.NewAuditEntry(actorID, models.AuditUserUnblocked, models.AuditTargetUser, userID, nil)
+	return s.store.SetBlocked(ctx, userID, nil, entry)
+}
+
+// UnlockUser lifts the lockout caused by failed login attempts.
+func (s *adminService) UnlockUser(ctx context.Context, actorID, userID string) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actorID, models.AuditUserUnlocked, models.AuditTargetUser, userID, nil)
+	return s.store.UnlockLogin(ctx, u.Login, entry)
+}
+
+func (s *adminService) RequeueOrder(ctx context.Context, actorID, numberOrder string) error {
+	entry := models.NewAuditEntry(actorID, models.AuditOrderRequeued, models.AuditTargetOrder, numberOrder, nil)
+	return s.store.RequeueOrder(ctx, numberOrder, entry)
+}
+
+// AdjustBalance credits a positive amount to the user balance or debits a
+// negative one. Reason and ticket are mandatory so every manual change can be
+// traced back to a support case.
+func (s *adminService) AdjustBalance(
+	ctx context.Context, actorID, userID string, req *dto.BalanceAdjustmentRequest) (*models.BalanceAdjustment, error) {
+
+	reason := strings.TrimSpace(req.Reason)
+	ticket := strings.TrimSpace(req.Ticket)
+	if req.Amount == 0 {
+		return nil, errs.ErrAdjustmentAmountInvalid
+	}
+	if reason == "" {
+		return nil, errs.ErrAdjustmentReasonRequired
+	}
+	if ticket == "" {
+		return nil, errs.ErrAdjustmentTicketRequired
+	}
+
+	adj := models.NewBalanceAdjustment(userID, req.Amount, reason, ticket, actorID)
+	entry := models.NewAuditEntry(actorID, models.AuditBalanceAdjust, models.AuditTargetUser, userID,
+		models.AuditDetails{"adjustment_id": adj.ID, "amount": adj.Amount.String(), "reason": reason, "ticket": ticket})
+	err := s.store.AdjustBalance(ctx, adj, entry)
+	if err != nil {
+		return nil, err
+	}
+	return adj, nil
+}
+
+func (s *adminService) GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error) {
+	_, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetBalanceAdjustments(ctx, userID)
+}
+
+func (s *adminService) GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error) {
+	return s.store.GetAuditLog(ctx, limit, offset)
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAdminStore struct {
+	mock.Mock
+}
+
+func (m *mockAdminStore) SearchUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error) {
+	args := m.Called(ctx, login, limit, offset)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockAdminStore) UpdateRoles(
+	ctx context.Context, userID string, roles models.Roles, entry *models.AuditEntry) error {
+	args := m.Called(ctx, userID, roles, entry)
+	return args.Error(0)
+}
+
+func (m *mockAdminStore) SetBlocked(
+	ctx context.Context, userID string, blockedAt *time.Time, entry *models.AuditEntry) error {
+	args := m.Called(ctx, userID, blockedAt, entry)
+	return args.Error(0)
+}
+
+func (m *mockAdminStore) UnlockLogin(ctx context.Context, login string, entry *models.AuditEntry) error {
+	args := m.Called(ctx, login, entry)
+	return args.Error(0)
+}
+
+func (m *mockAdminStore) RequeueOrder(ctx context.Context, numberOrder string, entry *models.AuditEntry) error {
+	args := m.Called(ctx, numberOrder, entry)
+	return args.Error(0)
+}
+
+func (m *mockAdminStore) AdjustBalance(
+	ctx context.Context, adj *models.BalanceAdjustment, entry *models.AuditEntry) error {
+	args := m.Called(ctx, adj, entry)
+	return args.Error(0)
+}
+
+func (m *mockAdminStore) GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.BalanceAdjustment), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockAdminStore) GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error) {
+	args := m.Called(ctx, limit, offset)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.AuditEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func auditEntry(actorID string, action models.AuditAction, targetID string) interface{} {
+	return mock.MatchedBy(func(e *models.AuditEntry) bool {
+		return e.ActorID == actorID && e.Action == action && e.TargetID == targetID
+	})
+}
+
+func TestAdminService_GrantRole(t *testing.T) {
+	user := &models.User{ID: "user123", Roles: models.Roles{models.RoleUser}}
+
+	tests := []struct {
+		name          string
+		role          models.Role
+		mockSetup     func(*mockAdminStore, *mockUserGetter)
+		expectedError error
+	}{
+		{
+			name: "успешная выдача роли",
+			role: models.RoleAdmin,
+			mockSetup: func(ms *mockAdminStore, mu *mockUserGetter) {
+				mu.On("GetByID", mock.Anything, "user123").Return(user, nil)
+				ms.On("UpdateRoles", mock.Anything, "user123",
+					models.Roles{models.RoleUser, models.RoleAdmin},
+					auditEntry("admin1", models.AuditRoleGranted, "user123")).Return(nil)
+			},
+		},
+		{
+			name: "роль уже выдана",
+			role: models.RoleUser,
+			mockSetup: func(ms *mockAdminStore, mu *mockUserGetter) {
+				mu.On("GetByID", mock.Anything, "user123").Return(user, nil)
+			},
+		},
+		{
+			name:          "неизвестная роль",
+			role:          models.Role("root"),
+			mockSetup:     func(ms *mockAdminStore, mu *mockUserGetter) {},
+			expectedError: errs.ErrRoleUnknown,
+		},
+		{
+			name: "пользователь не найден",
+			role: models.RoleAdmin,
+			mockSetup: func(ms *mockAdminStore, mu *mockUserGetter) {
+				mu.On("GetByID", mock.Anything, "user123").Return(nil, errs.ErrUserNotFound)
+			},
+			expectedError: errs.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockAdminStore)
+			users := new(mockUserGetter)
+			tt.mockSetup(store, users)
+
+			service := NewAdminService(store, users, nil, nil, new(mockSessionService))
+			err := service.GrantRole(context.Background(), "admin1", "user123", tt.role)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			store.AssertExpectations(t)
+			users.AssertExpectations(t)
+			require.Equal(t, models.Roles{models.RoleUser}, user.Roles)
+		})
+	}
+}
+
+func TestAdminService_RevokeRole(t *testing.T) {
+	t.Run("роль отозвана и сессии завершены", func(t *testing.T) {
+		store := new(mockAdminStore)
+		users := new(mockUserGetter)
+		sessions := new(mockSessionService)
+		users.On("GetByID", mock.Anything, "user123").
+			Return(&models.User{ID: "user123", Roles: models.Roles{models.RoleUser, models.RoleAdmin}}, nil)
+		store.On("UpdateRoles", mock.Anything, "user123", models.Roles{models.RoleUser},
+			auditEntry("admin1", models.AuditRoleRevoked, "user123")).Return(nil)
+		sessions.On("RevokeAll", mock.Anything, "user123").Return(nil)
+
+		service := NewAdminService(store, users, nil, nil, sessions)
+		err := service.RevokeRole(context.Background(), "admin1", "user123", models.RoleAdmin)
+		require.NoError(t, err)
+		store.AssertExpectations(t)
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("нельзя отозвать роль администратора у себя", func(t *testing.T) {
+		service := NewAdminService(new(mockAdminStore), new(mockUserGetter), nil, nil, new(mockSessionService))
+		err := service.RevokeRole(context.Background(), "admin1", "admin1", models.RoleAdmin)
+		require.ErrorIs(t, err, errs.ErrAdminSelfAction)
+	})
+}
+
+func TestAdminService_BlockUser(t *testing.T) {
+	t.Run("успешная блокировка", func(t *testing.T) {
+		store := new(mockAdminStore)
+		store.On("SetBlocked", mock.Anything, "user123",
+			mock.MatchedBy(func(at *time.Time) bool { return at != nil }),
+			mock.MatchedBy(func(e *models.AuditEntry) bool {
+				return e.Action == models.AuditUserBlocked && e.Details["reason"] == "fraud"
+			})).Return(nil)
+
+		service := NewAdminService(store, new(mockUserGetter), nil, nil, new(mockSessionService))
+		err := service.BlockUser(context.Background(), "admin1", "user123", "fraud")
+		require.NoError(t, err)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("разблокировка", func(t *testing.T) {
+		store := new(mockAdminStore)
+		store.On("SetBlocked", mock.Anything, "user123", (*time.Time)(nil),
+			auditEntry("admin1", models.AuditUserUnblocked, "user123")).Return(nil)
+
+		service := NewAdminService(store, new(mockUserGetter), nil, nil, new(mockSessionService))
+		err := service.UnblockUser(context.Background(), "admin1", "user123")
+		require.NoError(t, err)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("нельзя заблокировать себя", func(t *testing.T) {
+		service := NewAdminService(new(mockAdminStore), new(mockUserGetter), nil, nil, new(mockSessionService))
+		err := service.BlockUser(context.Background(), "admin1", "admin1", "")
+		require.ErrorIs(t, err, errs.ErrAdminSelfAction)
+	})
+}
+
+func TestAdminService_UnlockUser(t *testing.T) {
+	store := new(mockAdminStore)
+	users := new(mockUserGetter)
+	users.On("GetByID", mock.Anything, "user123").Return(&models.User{ID: "user123", Login: "testuser"}, nil)
+	store.On("UnlockLogin", mock.Anything, "testuser",
+		auditEntry("admin1", models.AuditUserUnlocked, "user123")).Return(nil)
+
+	service := NewAdminService(store, users, nil, nil, new(mockSessionService))
+	err := service.UnlockUser(context.Background(), "admin1", "user123")
+	require.NoError(t, err)
+	store.AssertExpectations(t)
+}
+
+func TestAdminService_GetUserOrders(t *testing.T) {
+	t.Run("заказы пользователя", func(t *testing.T) {
+		users := new(mockUserGetter)
+		orders := new(mockOrderCreator)
+		expected := []*models.Order{{Number: "12345678903", UserID: "user123"}}
+		users.On("GetByID", mock.Anything, "user123").Return(&models.User{ID: "user123"}, nil)
+		orders.On("GetUserOrders", mock.Anything, "user123").Return(expected, nil)
+
+		service := NewAdminService(new(mockAdminStore), users, orders, nil, new(mockSessionService))
+		result, err := service.GetUserOrders(context.Background(), "user123")
+		require.NoError(t, err)
+		require.Equal(t, expected, result)
+	})
+
+	t.Run("пользователь не найден", func(t *testing.T) {
+		users := new(mockUserGetter)
+		users.On("GetByID", mock.Anything, "user123").Return(nil, errs.ErrUserNotFound)
+
+		service := NewAdminService(new(mockAdminStore), users, new(mockOrderCreator), nil, new(mockSessionService))
+		_, err := service.GetUserOrders(context.Background(), "user123")
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+}
+
+func TestAdminService_AdjustBalance(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *dto.BalanceAdjustmentRequest
+		storeErr      error
+		callsStore    bool
+		expectedError error
+	}{
+		{
+			name:       "начисление",
+			req:        &dto.BalanceAdjustmentRequest{Amount: 10050, Reason: " compensation ", Ticket: "SUP-1"},
+			callsStore: true,
+		},
+		{
+			name:       "списание",
+			req:        &dto.BalanceAdjustmentRequest{Amount: -500, Reason: "fraud", Ticket: "SUP-2"},
+			callsStore: true,
+		},
+		{
+			name:          "списание больше баланса",
+			req:           &dto.BalanceAdjustmentRequest{Amount: -500, Reason: "fraud", Ticket: "SUP-2"},
+			callsStore:    true,
+			storeErr:      errs.ErrBalanceInsufficient,
+			expectedError: errs.ErrBalanceInsufficient,
+		},
+		{
+			name:          "нулевая сумма",
+			req:           &dto.BalanceAdjustmentRequest{Amount: 0, Reason: "fraud", Ticket: "SUP-2"},
+			expectedError: errs.ErrAdjustmentAmountInvalid,
+		},
+		{
+			name:          "без причины",
+			req:           &dto.BalanceAdjustmentRequest{Amount: 100, Reason: "  ", Ticket: "SUP-2"},
+			expectedError: errs.ErrAdjustmentReasonRequired,
+		},
+		{
+			name:          "без тикета",
+			req:           &dto.BalanceAdjustmentRequest{Amount: 100, Reason: "compensation"},
+			expectedError: errs.ErrAdjustmentTicketRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockAdminStore)
+			if tt.callsStore {
+				store.On("AdjustBalance", mock.Anything,
+					mock.MatchedBy(func(a *models.BalanceAdjustment) bool {
+						return a.UserID == "user123" && a.OperatorID == "admin1" &&
+							a.Amount == tt.req.Amount && a.Reason != "" && a.Reason == strings.TrimSpace(a.Reason)
+					}),
+					mock.MatchedBy(func(e *models.AuditEntry) bool {
+						return e.Action == models.AuditBalanceAdjust && e.Details["amount"] == tt.req.Amount.String()
+					})).Return(tt.storeErr)
+			}
+
+			service := NewAdminService(store, new(mockUserGetter), nil, nil, new(mockSessionService))
+			adj, err := service.AdjustBalance(context.Background(), "admin1", "user123", tt.req)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				require.Nil(t, adj)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.req.Amount, adj.Amount)
+			}
+			store.AssertExpectations(t)
+		})
+	}
+}
@@ -7,49 +7,176 @@ import (
 
 	"github.com/Soliard/gophermart/internal/dto"
 	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/logger"
 	"github.com/Soliard/gophermart/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/Soliard/gophermart/internal/tracing"
 )
 
+// maxLoginLength bounds logins accepted for authentication. Failed attempts
+// are tracked per login, so longer values would not fit the storage.
+const maxLoginLength = 255
+
 type UserLoginner interface {
+	UserGetter
 	GetByLogin(ctx context.Context, login string) (*models.User, error)
 	UpdateLoginTime(ctx context.Context, userID string, time time.Time) error
+	UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error
 }
 
 type authService struct {
-	loginner UserLoginner
-	jwt      JWTServiceInterface
+	loginner  UserLoginner
+	sessions  SessionServiceInterface
+	guard     LoginGuardInterface
+	hasher    PasswordHasher
+	jwt       JWTServiceInterface
+	twoFactor TwoFactorServiceInterface
 }
 
-func NewAuthService(userRepo UserLoginner, jwtService JWTServiceInterface) *authService {
+func NewAuthService(
+	userRepo UserLoginner,
+	sessions SessionServiceInterface,
+	guard LoginGuardInterface,
+	hasher PasswordHasher,
+	jwt JWTServiceInterface,
+	twoFactor TwoFactorServiceInterface) *authService {
+
 	return &authService{
-		loginner: userRepo,
-		jwt:      jwtService,
+		loginner:  userRepo,
+		sessions:  sessions,
+		guard:     guard,
+		hasher:    hasher,
+		jwt:       jwt,
+		twoFactor: twoFactor,
 	}
 }
 
-func (s *authService) Login(ctx context.Context, req *dto.LoginRequest) (string, error) {
+func (s *authService) Login(ctx context.Context, req *dto.LoginRequest, clientIP string) (*Tokens, error) {
+	ctx, span := tracing.Start(ctx, "authService.Login")
+	defer span.End()
+
+	if len(req.Login) > maxLoginLength {
+		return nil, errs.ErrLoginTooLong
+	}
+
 	now := time.Now().UTC()
+	err := s.guard.Check(ctx, req.Login, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
 	u, err := s.loginner.GetByLogin(ctx, req.Login)
 	if err != nil {
 		if errors.Is(err, errs.ErrUserNotFound) {
-			return "", errs.ErrWrongLoginOrPassword
+			return nil, s.failLogin(ctx, req.Login, clientIP, errs.ErrWrongLoginOrPassword)
+		}
+		return nil, err
+	}
+	ok, err := s.hasher.Verify(u.PasswordHash, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.failLogin(ctx, req.Login, clientIP, errs.ErrWrongLoginOrPassword)
+	}
+	s.rehashPassword(ctx, u, req.Password)
+	if u.Blocked() {
+		return nil, errs.ErrUserBlocked
+	}
+
+	if u.TwoFactorEnabled() {
+		challenge, err := s.jwt.GenerateChallengeToken(u.ID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &TwoFactorRequiredError{ChallengeToken: challenge}
+	}
+
+	return s.completeLogin(ctx, u, now)
+}
+
+// LoginTwoFactor finishes a login started with a correct password. A wrong
+// code counts as a failed login attempt, so the lockout also limits guessing
+// of TOTP and recovery codes.
+func (s *authService) LoginTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest, clientIP string) (*Tokens, error) {
+	ctx, span := tracing.Start(ctx, "authService.LoginTwoFactor")
+	defer span.End()
+
+	now := time.Now().UTC()
+	userID, err := s.jwt.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, errs.ErrTokenInvalid
+	}
+
+	u, err := s.loginner.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil, errs.ErrTokenInvalid
 		}
-		return "", err
+		return nil, err
+	}
+	err = s.guard.Check(ctx, u.Login, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	if u.Blocked() {
+		return nil, errs.ErrUserBlocked
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password))
+
+	err = s.twoFactor.Verify(ctx, u, req.Code)
 	if err != nil {
-		return "", errs.ErrWrongLoginOrPassword
+		if errors.Is(err, errs.ErrTwoFactorCodeInvalid) || errors.Is(err, errs.ErrTwoFactorNotEnrolled) {
+			return nil, s.failLogin(ctx, u.Login, clientIP, errs.ErrTwoFactorCodeInvalid)
+		}
+		return nil, err
 	}
 
-	tokenString, err := s.jwt.GenerateToken(u)
+	return s.completeLogin(ctx, u, now)
+}
+
+func (s *authService) completeLogin(ctx context.Context, u *models.User, now time.Time) (*Tokens, error) {
+	tokens, err := s.sessions.Start(ctx, u)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	u.LastLoginAt = &now
 
 	s.loginner.UpdateLoginTime(ctx, u.ID, now)
 
-	return tokenString, nil
+	err = s.guard.Reset(ctx, u.Login)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to reset failed login attempts", logger.F.Error(err))
+	}
+
+	return tokens, nil
+}
+
+// rehashPassword upgrades the stored hash after the password was verified if
+// it was made with an outdated algorithm or parameters. Failures are only
+// logged, the old hash keeps working.
+func (s *authService) rehashPassword(ctx context.Context, u *models.User, password string) {
+	if !s.hasher.NeedsRehash(u.PasswordHash) {
+		return
+	}
+	log := logger.FromContext(ctx)
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Error("Failed to rehash password", logger.F.Error(err))
+		return
+	}
+	err = s.loginner.UpdatePasswordHash(ctx, u.ID, hash)
+	if err != nil {
+		log.Error("Failed to store rehashed password", logger.F.Error(err))
+		return
+	}
+	u.PasswordHash = hash
+}
+
+// failLogin registers a failed attempt and returns cause to the caller.
+func (s *authService) failLogin(ctx context.Context, login, clientIP string, cause error) error {
+	err := s.guard.RegisterFailure(ctx, login, clientIP)
+	if err != nil {
+		return err
+	}
+	return cause
 }
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,13 +16,25 @@ import (
 )
 
 type mockUserLoginner struct {
-	mock.Mock
+	mockUserGetter
 }
 
 type mockJWTService struct {
 	mock.Mock
 }
 
+type mockSessionService struct {
+	mock.Mock
+}
+
+type mockLoginGuard struct {
+	mock.Mock
+}
+
+type mockTwoFactorService struct {
+	mock.Mock
+}
+
 func (m *mockUserLoginner) GetByLogin(ctx context.Context, login string) (*models.User, error) {
 	args := m.Called(ctx, login)
 	if v := args.Get(0); v != nil {
@@ -35,8 +48,8 @@ func (m *mockUserLoginner) UpdateLoginTime(ctx context.Context, userID string, t
 	return args.Error(0)
 }
 
-func (m *mockJWTService) GenerateToken(u *models.User) (string, error) {
-	args := m.Called(u)
+func (m *mockJWTService) GenerateToken(u *models.User, sessionID string) (string, error) {
+	args := m.Called(u, sessionID)
 	return args.String(0), args.Error(1)
 }
 
@@ -48,6 +61,102 @@ func (m *mockJWTService) GetClaims(tokenString string) (*UserContext, error) {
 	return nil, args.Error(1)
 }
 
+func (m *mockJWTService) GenerateChallengeToken(userID string) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockJWTService) ParseChallengeToken(tokenString string) (string, error) {
+	args := m.Called(tokenString)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockJWTService) JWKS() *dto.JSONWebKeySet {
+	args := m.Called()
+	return args.Get(0).(*dto.JSONWebKeySet)
+}
+
+func (m *mockSessionService) Start(ctx context.Context, u *models.User) (*Tokens, error) {
+	args := m.Called(ctx, u)
+	if v := args.Get(0); v != nil {
+		return v.(*Tokens), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSessionService) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	args := m.Called(ctx, refreshToken)
+	if v := args.Get(0); v != nil {
+		return v.(*Tokens), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSessionService) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockSessionService) Revoke(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockSessionService) RevokeAll(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserLoginner) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *mockLoginGuard) Check(ctx context.Context, login, clientIP string) error {
+	args := m.Called(ctx, login, clientIP)
+	return args.Error(0)
+}
+
+func (m *mockLoginGuard) RegisterFailure(ctx context.Context, login, clientIP string) error {
+	args := m.Called(ctx, login, clientIP)
+	return args.Error(0)
+}
+
+func (m *mockLoginGuard) Reset(ctx context.Context, login string) error {
+	args := m.Called(ctx, login)
+	return args.Error(0)
+}
+
+func (m *mockLoginGuard) DeleteStale(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockTwoFactorService) Enroll(ctx context.Context, userID string) (*dto.TwoFactorEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.(*dto.TwoFactorEnrollment), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if v := args.Get(0); v != nil {
+		return v.([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTwoFactorService) Verify(ctx context.Context, u *models.User, code string) error {
+	args := m.Called(ctx, u, code)
+	return args.Error(0)
+}
+
+func testPasswordHasher() PasswordHasher {
+	return NewPasswordHasher(NewBcryptHasher(bcrypt.DefaultCost))
+}
+
 func TestAuthService_Login(t *testing.T) {
 	validPassword := "correct_password"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(validPassword), bcrypt.DefaultCost)
@@ -62,8 +171,8 @@ func TestAuthService_Login(t *testing.T) {
 	tests := []struct {
 		name          string
 		loginReq      *dto.LoginRequest
-		mockSetup     func(*mockUserLoginner, *mockJWTService)
-		expectedToken string
+		mockSetup     func(*mockUserLoginner, *mockSessionService)
+		expectedToken *Tokens
 		expectedError error
 	}{
 		{
@@ -72,18 +181,18 @@ func TestAuthService_Login(t *testing.T) {
 				Login:    "testuser",
 				Password: validPassword,
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "testuser").
 					Return(testUser, nil)
 
-				mjwt.On("GenerateToken", testUser).
-					Return("valid-jwt-token", nil)
+				ms.On("Start", mock.Anything, testUser).
+					Return(&Tokens{Access: "valid-jwt-token", Refresh: "refresh-token"}, nil)
 
 				mul.On("UpdateLoginTime", mock.Anything, "user123", mock.MatchedBy(func(t time.Time) bool {
 					return !t.IsZero()
 				})).Return(nil)
 			},
-			expectedToken: "valid-jwt-token",
+			expectedToken: &Tokens{Access: "valid-jwt-token", Refresh: "refresh-token"},
 			expectedError: nil,
 		},
 		{
@@ -92,24 +201,38 @@ func TestAuthService_Login(t *testing.T) {
 				Login:    "testuser",
 				Password: "wrong_password",
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "testuser").
 					Return(testUser, nil)
 			},
-			expectedToken: "",
+			expectedToken: nil,
 			expectedError: errs.ErrWrongLoginOrPassword,
 		},
+		{
+			name: "пользователь заблокирован",
+			loginReq: &dto.LoginRequest{
+				Login:    "blocked",
+				Password: validPassword,
+			},
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
+				blockedAt := time.Now()
+				mul.On("GetByLogin", mock.Anything, "blocked").
+					Return(&models.User{ID: "user456", PasswordHash: string(hashedPassword), BlockedAt: &blockedAt}, nil)
+			},
+			expectedToken: nil,
+			expectedError: errs.ErrUserBlocked,
+		},
 		{
 			name: "пользователь не найден",
 			loginReq: &dto.LoginRequest{
 				Login:    "nonexistent",
 				Password: "anypassword",
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "nonexistent").
 					Return(nil, errs.ErrUserNotFound)
 			},
-			expectedToken: "",
+			expectedToken: nil,
 			expectedError: errs.ErrWrongLoginOrPassword,
 		},
 		{
@@ -118,28 +241,38 @@ func TestAuthService_Login(t *testing.T) {
 				Login:    "testuser",
 				Password: validPassword,
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "testuser").
 					Return(nil, errors.New("database error"))
 			},
-			expectedToken: "",
+			expectedToken: nil,
 			expectedError: errors.New("database error"),
 		},
+		{
+			name: "слишком длинный логин",
+			loginReq: &dto.LoginRequest{
+				Login:    strings.Repeat("a", 256),
+				Password: validPassword,
+			},
+			mockSetup:     func(mul *mockUserLoginner, ms *mockSessionService) {},
+			expectedToken: nil,
+			expectedError: errs.ErrLoginTooLong,
+		},
 		{
 			name: "ошибка генерации токена",
 			loginReq: &dto.LoginRequest{
 				Login:    "testuser",
 				Password: validPassword,
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "testuser").
 					Return(testUser, nil)
 
-				mjwt.On("GenerateToken", testUser).
-					Return("", errors.New("jwt generation error"))
+				ms.On("Start", mock.Anything, testUser).
+					Return(nil, errors.New("jwt generation error"))
 
 			},
-			expectedToken: "",
+			expectedToken: nil,
 			expectedError: errors.New("jwt generation error"),
 		},
 		{
@@ -148,17 +281,17 @@ func TestAuthService_Login(t *testing.T) {
 				Login:    "testuser",
 				Password: validPassword,
 			},
-			mockSetup: func(mul *mockUserLoginner, mjwt *mockJWTService) {
+			mockSetup: func(mul *mockUserLoginner, ms *mockSessionService) {
 				mul.On("GetByLogin", mock.Anything, "testuser").
 					Return(testUser, nil)
 
-				mjwt.On("GenerateToken", testUser).
-					Return("valid-token", nil)
+				ms.On("Start", mock.Anything, testUser).
+					Return(&Tokens{Access: "valid-token", Refresh: "refresh-token"}, nil)
 
 				mul.On("UpdateLoginTime", mock.Anything, "user123", mock.Anything).
 					Return(errors.New("update failed"))
 			},
-			expectedToken: "valid-token",
+			expectedToken: &Tokens{Access: "valid-token", Refresh: "refresh-token"},
 			expectedError: nil,
 		},
 	}
@@ -166,14 +299,20 @@ func TestAuthService_Login(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockUserRepo := new(mockUserLoginner)
-			mockJWT := new(mockJWTService)
+			mockSessions := new(mockSessionService)
+
+			mockGuard := new(mockLoginGuard)
+			mockGuard.On("Check", mock.Anything, tt.loginReq.Login, "10.0.0.1").Return(nil)
+			mockGuard.On("RegisterFailure", mock.Anything, tt.loginReq.Login, "10.0.0.1").Return(nil).Maybe()
+			mockGuard.On("Reset", mock.Anything, tt.loginReq.Login).Return(nil).Maybe()
 
-			tt.mockSetup(mockUserRepo, mockJWT)
+			tt.mockSetup(mockUserRepo, mockSessions)
 
-			service := NewAuthService(mockUserRepo, mockJWT)
+			service := NewAuthService(mockUserRepo, mockSessions, mockGuard, testPasswordHasher(),
+				new(mockJWTService), new(mockTwoFactorService))
 
 			ctx := context.Background()
-			token, err := service.Login(ctx, tt.loginReq)
+			token, err := service.Login(ctx, tt.loginReq, "10.0.0.1")
 
 			if tt.expectedError != nil {
 				require.Error(t, err)
@@ -185,7 +324,220 @@ func TestAuthService_Login(t *testing.T) {
 			require.Equal(t, tt.expectedToken, token)
 
 			mockUserRepo.AssertExpectations(t)
-			mockJWT.AssertExpectations(t)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_Login_Lockout(t *testing.T) {
+	validPassword := "correct_password"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(validPassword), bcrypt.DefaultCost)
+	testUser := &models.User{ID: "user123", Login: "testuser", PasswordHash: string(hashedPassword)}
+
+	t.Run("заблокированный логин не проверяет пароль", func(t *testing.T) {
+		users := new(mockUserLoginner)
+		guard := new(mockLoginGuard)
+		guard.On("Check", mock.Anything, "testuser", "10.0.0.1").
+			Return(&LoginLockedError{RetryAfter: time.Minute})
+
+		service := NewAuthService(users, new(mockSessionService), guard, testPasswordHasher(),
+			new(mockJWTService), new(mockTwoFactorService))
+		_, err := service.Login(context.Background(),
+			&dto.LoginRequest{Login: "testuser", Password: validPassword}, "10.0.0.1")
+
+		var locked *LoginLockedError
+		require.ErrorAs(t, err, &locked)
+		require.ErrorIs(t, err, errs.ErrLoginLocked)
+		require.Equal(t, time.Minute, locked.RetryAfter)
+		users.AssertNotCalled(t, "GetByLogin", mock.Anything, mock.Anything)
+	})
+
+	t.Run("неудачная попытка учитывается", func(t *testing.T) {
+		users := new(mockUserLoginner)
+		guard := new(mockLoginGuard)
+		users.On("GetByLogin", mock.Anything, "testuser").Return(testUser, nil)
+		guard.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+		guard.On("RegisterFailure", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+
+		service := NewAuthService(users, new(mockSessionService), guard, testPasswordHasher(),
+			new(mockJWTService), new(mockTwoFactorService))
+		_, err := service.Login(context.Background(),
+			&dto.LoginRequest{Login: "testuser", Password: "wrong"}, "10.0.0.1")
+		require.ErrorIs(t, err, errs.ErrWrongLoginOrPassword)
+		guard.AssertExpectations(t)
+	})
+
+	t.Run("успешный вход сбрасывает счетчик", func(t *testing.T) {
+		users := new(mockUserLoginner)
+		sessions := new(mockSessionService)
+		guard := new(mockLoginGuard)
+		users.On("GetByLogin", mock.Anything, "testuser").Return(testUser, nil)
+		users.On("UpdateLoginTime", mock.Anything, "user123", mock.Anything).Return(nil)
+		sessions.On("Start", mock.Anything, testUser).Return(&Tokens{Access: "a", Refresh: "r"}, nil)
+		guard.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+		guard.On("Reset", mock.Anything, "testuser").Return(nil)
+
+		service := NewAuthService(users, sessions, guard, testPasswordHasher(),
+			new(mockJWTService), new(mockTwoFactorService))
+		_, err := service.Login(context.Background(),
+			&dto.LoginRequest{Login: "testuser", Password: validPassword}, "10.0.0.1")
+		require.NoError(t, err)
+		guard.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_Login_Rehash(t *testing.T) {
+	password := "correct_password"
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	argon2id := NewArgon2idHasher(1024, 1, 1)
+	upToDate, err := argon2id.Hash(password)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		storedHash   string
+		expectRehash bool
+	}{
+		{name: "bcrypt хеш обновляется до argon2id", storedHash: string(bcryptHash), expectRehash: true},
+		{name: "актуальный хеш не обновляется", storedHash: upToDate, expectRehash: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &models.User{ID: "user123", Login: "testuser", PasswordHash: tt.storedHash}
+			users := new(mockUserLoginner)
+			sessions := new(mockSessionService)
+			guard := new(mockLoginGuard)
+			users.On("GetByLogin", mock.Anything, "testuser").Return(u, nil)
+			users.On("UpdateLoginTime", mock.Anything, "user123", mock.Anything).Return(nil)
+			sessions.On("Start", mock.Anything, u).Return(&Tokens{Access: "a", Refresh: "r"}, nil)
+			guard.On("Check", mock.Anything, "testuser", "").Return(nil)
+			guard.On("Reset", mock.Anything, "testuser").Return(nil)
+			if tt.expectRehash {
+				users.On("UpdatePasswordHash", mock.Anything, "user123", mock.MatchedBy(func(h string) bool {
+					ok, err := argon2id.Verify(h, password)
+					return err == nil && ok
+				})).Return(nil)
+			}
+
+			hasher := NewPasswordHasher(argon2id, NewBcryptHasher(bcrypt.MinCost))
+			service := NewAuthService(users, sessions, guard, hasher,
+				new(mockJWTService), new(mockTwoFactorService))
+			_, err := service.Login(context.Background(), &dto.LoginRequest{Login: "testuser", Password: password}, "")
+			require.NoError(t, err)
+			users.AssertExpectations(t)
+			if !tt.expectRehash {
+				users.AssertNotCalled(t, "UpdatePasswordHash", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAuthService_Login_TwoFactor(t *testing.T) {
+	password := "correct_password"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	enabledAt := time.Now()
+	newUser := func() *models.User {
+		return &models.User{ID: "user123", Login: "testuser", PasswordHash: string(hashedPassword),
+			TOTPSecret: &secret, TOTPEnabledAt: &enabledAt}
+	}
+
+	t.Run("пароль верный, требуется код", func(t *testing.T) {
+		users := new(mockUserLoginner)
+		sessions := new(mockSessionService)
+		guard := new(mockLoginGuard)
+		jwt := new(mockJWTService)
+		users.On("GetByLogin", mock.Anything, "testuser").Return(newUser(), nil)
+		guard.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+		jwt.On("GenerateChallengeToken", "user123").Return("challenge", nil)
+
+		service := NewAuthService(users, sessions, guard, testPasswordHasher(), jwt, new(mockTwoFactorService))
+		tokens, err := service.Login(context.Background(),
+			&dto.LoginRequest{Login: "testuser", Password: password}, "10.0.0.1")
+
+		var required *TwoFactorRequiredError
+		require.ErrorAs(t, err, &required)
+		require.ErrorIs(t, err, errs.ErrTwoFactorRequired)
+		require.Equal(t, "challenge", required.ChallengeToken)
+		require.Nil(t, tokens)
+		sessions.AssertNotCalled(t, "Start", mock.Anything, mock.Anything)
+		guard.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name          string
+		code          string
+		mockSetup     func(*mockJWTService, *mockTwoFactorService, *mockLoginGuard, *mockSessionService)
+		expectedError error
+	}{
+		{
+			name: "верный код завершает вход",
+			code: "123456",
+			mockSetup: func(mj *mockJWTService, mt *mockTwoFactorService, mg *mockLoginGuard, ms *mockSessionService) {
+				mj.On("ParseChallengeToken", "challenge").Return("user123", nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+				mt.On("Verify", mock.Anything, mock.Anything, "123456").Return(nil)
+				ms.On("Start", mock.Anything, mock.Anything).Return(&Tokens{Access: "a", Refresh: "r"}, nil)
+				mg.On("Reset", mock.Anything, "testuser").Return(nil)
+			},
+		},
+		{
+			name: "неверный код учитывается как неудачная попытка",
+			code: "000000",
+			mockSetup: func(mj *mockJWTService, mt *mockTwoFactorService, mg *mockLoginGuard, ms *mockSessionService) {
+				mj.On("ParseChallengeToken", "challenge").Return("user123", nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+				mt.On("Verify", mock.Anything, mock.Anything, "000000").Return(errs.ErrTwoFactorCodeInvalid)
+				mg.On("RegisterFailure", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+			},
+			expectedError: errs.ErrTwoFactorCodeInvalid,
+		},
+		{
+			name: "недействительный challenge токен",
+			code: "123456",
+			mockSetup: func(mj *mockJWTService, mt *mockTwoFactorService, mg *mockLoginGuard, ms *mockSessionService) {
+				mj.On("ParseChallengeToken", "challenge").Return("", errs.ErrTokenInvalid)
+			},
+			expectedError: errs.ErrTokenInvalid,
+		},
+		{
+			name: "попытки заблокированы",
+			code: "123456",
+			mockSetup: func(mj *mockJWTService, mt *mockTwoFactorService, mg *mockLoginGuard, ms *mockSessionService) {
+				mj.On("ParseChallengeToken", "challenge").Return("user123", nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").
+					Return(&LoginLockedError{RetryAfter: time.Minute})
+			},
+			expectedError: errs.ErrLoginLocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(mockUserLoginner)
+			sessions := new(mockSessionService)
+			guard := new(mockLoginGuard)
+			jwt := new(mockJWTService)
+			twoFactor := new(mockTwoFactorService)
+			users.On("GetByID", mock.Anything, "user123").Return(newUser(), nil).Maybe()
+			users.On("UpdateLoginTime", mock.Anything, "user123", mock.Anything).Return(nil).Maybe()
+			tt.mockSetup(jwt, twoFactor, guard, sessions)
+
+			service := NewAuthService(users, sessions, guard, testPasswordHasher(), jwt, twoFactor)
+			tokens, err := service.LoginTwoFactor(context.Background(),
+				&dto.TwoFactorLoginRequest{ChallengeToken: "challenge", Code: tt.code}, "10.0.0.1")
+
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				require.Nil(t, tokens)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, &Tokens{Access: "a", Refresh: "r"}, tokens)
+			}
+			guard.AssertExpectations(t)
+			twoFactor.AssertExpectations(t)
+			sessions.AssertExpectations(t)
 		})
 	}
 }
@@ -3,11 +3,15 @@ package services
 import (
 	"context"
 
+	"github.com/Soliard/gophermart/internal/logger"
 	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/tracing"
 )
 
 type BalanceProvider interface {
 	GetUserBalance(ctx context.Context, userID string) (*models.Balance, error)
+	GetBalanceMismatches(ctx context.Context) ([]*models.BalanceMismatch, error)
+	GetUserLedger(ctx context.Context, userID string) ([]*models.LedgerEntry, error)
 }
 
 type balanceService struct {
@@ -21,5 +25,33 @@ func NewBalanceService(repo BalanceProvider) *balanceService {
 }
 
 func (s *balanceService) GetBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	ctx, span := tracing.Start(ctx, "balanceService.GetBalance")
+	defer span.End()
+
 	return s.repo.GetUserBalance(ctx, userID)
 }
+
+func (s *balanceService) GetHistory(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	ctx, span := tracing.Start(ctx, "balanceService.GetHistory")
+	defer span.End()
+
+	return s.repo.GetUserLedger(ctx, userID)
+}
+
+// Reconcile compares materialized balances with the sums of orders,
+// withdrawals and manual adjustments and reports every user whose balance
+// has drifted.
+func (s *balanceService) Reconcile(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	ctx, span := tracing.Start(ctx, "balanceService.Reconcile")
+	defer span.End()
+
+	log := logger.FromContext(ctx)
+	mismatches, err := s.repo.GetBalanceMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mismatches {
+		log.Error("Balance does not match orders, withdrawals and adjustments", logger.F.Any("mismatch", m))
+	}
+	return mismatches, nil
+}
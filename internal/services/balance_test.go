@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockBalanceProvider struct {
+	mock.Mock
+}
+
+func (m *mockBalanceProvider) GetUserBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Balance), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockBalanceProvider) GetBalanceMismatches(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	args := m.Called(ctx)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.BalanceMismatch), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockBalanceProvider) GetUserLedger(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	args := m.Called(ctx, userID)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.LedgerEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestBalanceService_GetHistory(t *testing.T) {
+	now := time.Now().UTC()
+	newest := &models.LedgerEntry{Kind: models.LedgerEntryWithdrawal, Amount: -20000, CreatedAt: now}
+	oldest := &models.LedgerEntry{Kind: models.LedgerEntryAccrual, Amount: 50000, CreatedAt: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name            string
+		entries         []*models.LedgerEntry
+		repoErr         error
+		expectedEntries []*models.LedgerEntry
+	}{
+		{
+			name:            "записи от новых к старым",
+			entries:         []*models.LedgerEntry{newest, oldest},
+			expectedEntries: []*models.LedgerEntry{newest, oldest},
+		},
+		{
+			name:            "пустая история",
+			entries:         []*models.LedgerEntry{},
+			expectedEntries: []*models.LedgerEntry{},
+		},
+		{
+			name:    "ошибка базы данных",
+			repoErr: errors.New("database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockBalanceProvider)
+			repo.On("GetUserLedger", mock.Anything, "user123").Return(tt.entries, tt.repoErr)
+
+			service := NewBalanceService(repo)
+			entries, err := service.GetHistory(context.Background(), "user123")
+			if tt.repoErr != nil {
+				require.ErrorIs(t, err, tt.repoErr)
+				require.Nil(t, entries)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedEntries, entries)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBalanceService_Reconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		mismatches    []*models.BalanceMismatch
+		repoErr       error
+		expectedCount int
+	}{
+		{
+			name:          "балансы сходятся",
+			mismatches:    []*models.BalanceMismatch{},
+			expectedCount: 0,
+		},
+		{
+			name: "найдено расхождение",
+			mismatches: []*models.BalanceMismatch{
+				{UserID: "user123", Current: 10000, ExpectedCurrent: 9000},
+			},
+			expectedCount: 1,
+		},
+		{
+			name:    "ошибка базы данных",
+			repoErr: errors.New("database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockBalanceProvider)
+			if tt.repoErr != nil {
+				repo.On("GetBalanceMismatches", mock.Anything).Return(nil, tt.repoErr)
+			} else {
+				repo.On("GetBalanceMismatches", mock.Anything).Return(tt.mismatches, nil)
+			}
+
+			service := NewBalanceService(repo)
+			mismatches, err := service.Reconcile(context.Background())
+
+			if tt.repoErr != nil {
+				require.ErrorIs(t, err, tt.repoErr)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, mismatches, tt.expectedCount)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+)
+
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+
+	healthCheckTimeout = 2 * time.Second
+)
+
+// HealthCheck is a readiness dependency. A failing non critical check is
+// reported but does not make the instance unready: users can still log in
+// and upload orders while the accrual system is down.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type healthService struct {
+	checks       []HealthCheck
+	shuttingDown atomic.Bool
+}
+
+func NewHealthService(checks ...HealthCheck) *healthService {
+	return &healthService{
+		checks: checks,
+	}
+}
+
+// Readiness runs all checks concurrently, each one limited by healthCheckTimeout.
+func (s *healthService) Readiness(ctx context.Context) *dto.HealthResponse {
+	resp := &dto.HealthResponse{
+		Status: HealthStatusOK,
+		Checks: make(map[string]*dto.HealthCheckResult, len(s.checks)+1),
+	}
+	if s.shuttingDown.Load() {
+		resp.Status = HealthStatusFail
+		resp.Checks["shutdown"] = &dto.HealthCheckResult{
+			Status: HealthStatusFail, Critical: true, Error: errs.ErrShuttingDown.Error(),
+		}
+	}
+
+	results := make([]*dto.HealthCheckResult, len(s.checks))
+	var wg sync.WaitGroup
+	for i, c := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			result := &dto.HealthCheckResult{Status: HealthStatusOK, Critical: c.Critical}
+			err := c.Check(checkCtx)
+			if err != nil {
+				result.Status = HealthStatusFail
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range s.checks {
+		resp.Checks[c.Name] = results[i]
+		if c.Critical && results[i].Status != HealthStatusOK {
+			resp.Status = HealthStatusFail
+		}
+	}
+	return resp
+}
+
+// Shutdown makes the instance unready, so that the load balancer stops
+// routing new requests to it while in-flight ones are drained.
+func (s *healthService) Shutdown() {
+	s.shuttingDown.Store(true)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthService_Readiness(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name           string
+		checks         []HealthCheck
+		shutdown       bool
+		expectedStatus string
+	}{
+		{
+			name: "все проверки пройдены",
+			checks: []HealthCheck{
+				{Name: "database", Critical: true, Check: ok},
+				{Name: "accrual", Check: ok},
+			},
+			expectedStatus: HealthStatusOK,
+		},
+		{
+			name: "некритичная проверка не влияет на готовность",
+			checks: []HealthCheck{
+				{Name: "database", Critical: true, Check: ok},
+				{Name: "accrual", Check: fail},
+			},
+			expectedStatus: HealthStatusOK,
+		},
+		{
+			name: "критичная проверка не пройдена",
+			checks: []HealthCheck{
+				{Name: "database", Critical: true, Check: fail},
+				{Name: "accrual", Check: ok},
+			},
+			expectedStatus: HealthStatusFail,
+		},
+		{
+			name:           "остановка сервера",
+			checks:         []HealthCheck{{Name: "database", Critical: true, Check: ok}},
+			shutdown:       true,
+			expectedStatus: HealthStatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewHealthService(tt.checks...)
+			if tt.shutdown {
+				service.Shutdown()
+			}
+
+			resp := service.Readiness(context.Background())
+			require.Equal(t, tt.expectedStatus, resp.Status)
+			for _, c := range tt.checks {
+				require.Contains(t, resp.Checks, c.Name)
+				require.Equal(t, c.Critical, resp.Checks[c.Name].Critical)
+			}
+			if tt.shutdown {
+				require.Equal(t, errs.ErrShuttingDown.Error(), resp.Checks["shutdown"].Error)
+			}
+		})
+	}
+}
+
+func TestHealthService_Readiness_Timeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	service := NewHealthService(HealthCheck{Name: "database", Critical: true, Check: slow})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := service.Readiness(ctx)
+	require.Equal(t, HealthStatusFail, resp.Status)
+	require.Equal(t, context.Canceled.Error(), resp.Checks["database"].Error)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLease is how long a request holds its key. A key left in progress
+// by a crashed request can be taken over by a retry after that.
+const idempotencyLease = time.Minute
+
+type IdempotencyStore interface {
+	Create(ctx context.Context, rec *models.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error)
+	Reclaim(ctx context.Context, userID, key string, lockedUntil time.Time) (bool, error)
+	// SaveResponse and Delete only touch a key still leased until
+	// lockedUntil, so a request whose lease was taken over cannot overwrite
+	// or drop the key of the request that took it over.
+	SaveResponse(ctx context.Context, rec *models.IdempotencyRecord) error
+	Delete(ctx context.Context, userID, key string, lockedUntil time.Time) error
+	DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type idempotencyService struct {
+	store IdempotencyStore
+	ttl   time.Duration
+}
+
+func NewIdempotencyService(store IdempotencyStore, ttl time.Duration) *idempotencyService {
+	return &idempotencyService{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Begin reserves the key for a new request and returns the reservation to
+// pass to Complete or Release. If the key was already used for the same
+// request, the completed record is returned and must be replayed instead of
+// executing the request again. A reservation whose lease expired without a
+// response is taken over.
+func (s *idempotencyService) Begin(ctx context.Context, userID, key, requestHash string) (*models.IdempotencyRecord, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, errs.ErrIdempotencyKeyTooLong
+	}
+
+	reservation := models.NewIdempotencyRecord(userID, key, requestHash, idempotencyLease)
+	created, err := s.store.Create(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return reservation, nil
+	}
+
+	rec, err := s.store.Get(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, errs.ErrIdempotencyKeyNotFound) {
+			return nil, errs.ErrIdempotencyKeyInProgress
+		}
+		return nil, err
+	}
+	if rec.RequestHash != requestHash {
+		return nil, errs.ErrIdempotencyKeyReused
+	}
+	if rec.Completed() {
+		return rec, nil
+	}
+
+	now := time.Now().UTC()
+	if rec.Locked(now) {
+		return nil, errs.ErrIdempotencyKeyInProgress
+	}
+	lockedUntil := models.IdempotencyLeaseUntil(now, idempotencyLease)
+	reclaimed, err := s.store.Reclaim(ctx, userID, key, lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if !reclaimed {
+		return nil, errs.ErrIdempotencyKeyInProgress
+	}
+	reclaimedRec := *rec
+	reclaimedRec.LockedUntil = &lockedUntil
+	return &reclaimedRec, nil
+}
+
+// Complete stores the response of the reservation. It fails with
+// ErrIdempotencyLeaseLost if the lease expired and another request took the
+// key over.
+func (s *idempotencyService) Complete(ctx context.Context, rec *models.IdempotencyRecord) error {
+	return s.store.SaveResponse(ctx, rec)
+}
+
+// Release forgets the reservation so that the request may be retried, e.g.
+// after an internal error.
+func (s *idempotencyService) Release(ctx context.Context, rec *models.IdempotencyRecord) error {
+	return s.store.Delete(ctx, rec.UserID, rec.Key, *rec.LockedUntil)
+}
+
+func (s *idempotencyService) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.store.DeleteCreatedBefore(ctx, time.Now().UTC().Add(-s.ttl))
+}
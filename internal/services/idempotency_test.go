@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockIdempotencyStore struct {
+	mock.Mock
+}
+
+func (m *mockIdempotencyStore) Create(ctx context.Context, rec *models.IdempotencyRecord) (bool, error) {
+	args := m.Called(ctx, rec)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockIdempotencyStore) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	args := m.Called(ctx, userID, key)
+	if v := args.Get(0); v != nil {
+		return v.(*models.IdempotencyRecord), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockIdempotencyStore) Reclaim(ctx context.Context, userID, key string, lockedUntil time.Time) (bool, error) {
+	args := m.Called(ctx, userID, key, lockedUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockIdempotencyStore) SaveResponse(ctx context.Context, rec *models.IdempotencyRecord) error {
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *mockIdempotencyStore) Delete(ctx context.Context, userID, key string, lockedUntil time.Time) error {
+	args := m.Called(ctx, userID, key, lockedUntil)
+	return args.Error(0)
+}
+
+func (m *mockIdempotencyStore) DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	completed := &models.IdempotencyRecord{
+		UserID:      "user123",
+		Key:         "key-1",
+		RequestHash: "hash",
+		StatusCode:  202,
+	}
+	lockedUntil := time.Now().Add(time.Minute)
+	inProgress := &models.IdempotencyRecord{
+		UserID:      "user123",
+		Key:         "key-1",
+		RequestHash: "hash",
+		LockedUntil: &lockedUntil,
+	}
+	expiredAt := time.Now().Add(-time.Minute)
+	abandoned := &models.IdempotencyRecord{
+		UserID:      "user123",
+		Key:         "key-1",
+		RequestHash: "hash",
+		LockedUntil: &expiredAt,
+	}
+
+	tests := []struct {
+		name           string
+		key            string
+		hash           string
+		mockSetup      func(*mockIdempotencyStore)
+		expectedRecord *models.IdempotencyRecord
+		reserved       bool
+		expectedError  error
+	}{
+		{
+			name: "новый ключ",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.MatchedBy(func(r *models.IdempotencyRecord) bool {
+					return r.UserID == "user123" && r.Key == "key-1" && r.RequestHash == "hash"
+				})).Return(true, nil)
+			},
+			reserved: true,
+		},
+		{
+			name: "повтор завершённого запроса",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+				m.On("Get", mock.Anything, "user123", "key-1").Return(completed, nil)
+			},
+			expectedRecord: completed,
+		},
+		{
+			name: "запрос ещё выполняется",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+				m.On("Get", mock.Anything, "user123", "key-1").Return(inProgress, nil)
+			},
+			expectedError: errs.ErrIdempotencyKeyInProgress,
+		},
+		{
+			name: "брошенный запрос перехватывается после истечения аренды",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+				m.On("Get", mock.Anything, "user123", "key-1").Return(abandoned, nil)
+				m.On("Reclaim", mock.Anything, "user123", "key-1", mock.MatchedBy(func(until time.Time) bool {
+					return until.After(time.Now())
+				})).Return(true, nil)
+			},
+			reserved: true,
+		},
+		{
+			name: "брошенный запрос уже перехвачен другим повтором",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+				m.On("Get", mock.Anything, "user123", "key-1").Return(abandoned, nil)
+				m.On("Reclaim", mock.Anything, "user123", "key-1", mock.Anything).Return(false, nil)
+			},
+			expectedError: errs.ErrIdempotencyKeyInProgress,
+		},
+		{
+			name: "ключ использован для другого запроса",
+			key:  "key-1",
+			hash: "other-hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+				m.On("Get", mock.Anything, "user123", "key-1").Return(completed, nil)
+			},
+			expectedError: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			name:          "слишком длинный ключ",
+			key:           strings.Repeat("k", 256),
+			hash:          "hash",
+			mockSetup:     func(m *mockIdempotencyStore) {},
+			expectedError: errs.ErrIdempotencyKeyTooLong,
+		},
+		{
+			name: "ошибка базы данных",
+			key:  "key-1",
+			hash: "hash",
+			mockSetup: func(m *mockIdempotencyStore) {
+				m.On("Create", mock.Anything, mock.Anything).Return(false, errors.New("database error"))
+			},
+			expectedError: errors.New("database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockIdempotencyStore)
+			tt.mockSetup(store)
+
+			service := NewIdempotencyService(store, time.Hour)
+			rec, err := service.Begin(context.Background(), "user123", tt.key, tt.hash)
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedError.Error())
+				require.Nil(t, rec)
+			} else if tt.reserved {
+				require.NoError(t, err)
+				require.False(t, rec.Completed())
+				require.True(t, rec.Locked(time.Now()))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedRecord, rec)
+			}
+			store.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdempotencyService_Release(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Minute)
+	rec := &models.IdempotencyRecord{UserID: "user123", Key: "key-1", LockedUntil: &lockedUntil}
+
+	store := new(mockIdempotencyStore)
+	store.On("Delete", mock.Anything, "user123", "key-1", lockedUntil).Return(nil)
+
+	service := NewIdempotencyService(store, time.Hour)
+	require.NoError(t, service.Release(context.Background(), rec))
+	store.AssertExpectations(t)
+}
+
+func TestIdempotencyService_DeleteExpired(t *testing.T) {
+	store := new(mockIdempotencyStore)
+	store.On("DeleteCreatedBefore", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		expected := time.Now().Add(-24 * time.Hour)
+		return before.Sub(expected).Abs() < time.Minute
+	})).Return(int64(3), nil)
+
+	service := NewIdempotencyService(store, 24*time.Hour)
+	deleted, err := service.DeleteExpired(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), deleted)
+	store.AssertExpectations(t)
+}
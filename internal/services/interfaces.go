@@ -8,7 +8,33 @@ import (
 )
 
 type AuthServiceInterface interface {
-	Login(ctx context.Context, req *dto.LoginRequest) (token string, err error)
+	Login(ctx context.Context, req *dto.LoginRequest, clientIP string) (*Tokens, error)
+	LoginTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest, clientIP string) (*Tokens, error)
+}
+
+type TwoFactorServiceInterface interface {
+	Enroll(ctx context.Context, userID string) (*dto.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userID, code string) ([]string, error)
+	Verify(ctx context.Context, u *models.User, code string) error
+}
+
+type PasswordServiceInterface interface {
+	ChangePassword(ctx context.Context, userID, clientIP string, req *dto.ChangePasswordRequest) (*Tokens, error)
+}
+
+type LoginGuardInterface interface {
+	Check(ctx context.Context, login, clientIP string) error
+	RegisterFailure(ctx context.Context, login, clientIP string) error
+	Reset(ctx context.Context, login string) error
+	DeleteStale(ctx context.Context) (int64, error)
+}
+
+type SessionServiceInterface interface {
+	Start(ctx context.Context, u *models.User) (*Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+	Revoke(ctx context.Context, sessionID string) error
+	RevokeAll(ctx context.Context, userID string) error
 }
 
 type RegistrationServiceInterface interface {
@@ -22,19 +48,53 @@ type OrderServiceInterface interface {
 }
 
 type JWTServiceInterface interface {
-	GenerateToken(u *models.User) (string, error)
+	GenerateToken(u *models.User, sessionID string) (string, error)
 	GetClaims(token string) (*UserContext, error)
+	GenerateChallengeToken(userID string) (string, error)
+	ParseChallengeToken(token string) (string, error)
+	JWKS() *dto.JSONWebKeySet
 }
 
 type AccrualServiceInterface interface {
 	UpdateOrders(ctx context.Context) error
+	Ping(ctx context.Context) error
+}
+
+type HealthServiceInterface interface {
+	Readiness(ctx context.Context) *dto.HealthResponse
+	Shutdown()
 }
 
 type WithdrawalServiceInterface interface {
-	ProcessWithdraw(ctx context.Context, userID, orderNumber string, sum float64) error
+	ProcessWithdraw(ctx context.Context, userID, orderNumber string, sum models.Points) error
 	GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error)
 }
 
 type BalanceServiceInterface interface {
 	GetBalance(ctx context.Context, userID string) (*models.Balance, error)
+	GetHistory(ctx context.Context, userID string) ([]*models.LedgerEntry, error)
+	Reconcile(ctx context.Context) ([]*models.BalanceMismatch, error)
+}
+
+type IdempotencyServiceInterface interface {
+	Begin(ctx context.Context, userID, key, requestHash string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, rec *models.IdempotencyRecord) error
+	Release(ctx context.Context, rec *models.IdempotencyRecord) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type AdminServiceInterface interface {
+	ListUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error)
+	GetUserOrders(ctx context.Context, userID string) ([]*models.Order, error)
+	GetUserWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error)
+	GrantRole(ctx context.Context, actorID, userID string, role models.Role) error
+	RevokeRole(ctx context.Context, actorID, userID string, role models.Role) error
+	BlockUser(ctx context.Context, actorID, userID, reason string) error
+	UnblockUser(ctx context.Context, actorID, userID string) error
+	UnlockUser(ctx context.Context, actorID, userID string) error
+	RequeueOrder(ctx context.Context, actorID, numberOrder string) error
+	AdjustBalance(ctx context.Context, actorID, userID string,
+		req *dto.BalanceAdjustmentRequest) (*models.BalanceAdjustment, error)
+	GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error)
+	GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error)
 }
@@ -3,8 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/Soliard/gophermart/internal/dto"
 	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/models"
 	"github.com/golang-jwt/jwt/v4"
@@ -12,54 +15,127 @@ import (
 
 const ctxKeyUserContext ctxKey = "user"
 
-var signingMethod jwt.SigningMethod = jwt.SigningMethodHS256
+const (
+	purposeTwoFactor      = "2fa"
+	challengeExpiresAfter = 5 * time.Minute
+)
 
 type ctxKey string
 
 type jwtService struct {
-	secret       string
+	signer       *SigningKey
+	keys         map[string]*SigningKey
 	expiresAfter time.Duration
 }
 
 type UserContext struct {
-	ID    string        `json:"id"`
-	Roles []models.Role `json:"roles"`
+	ID        string        `json:"id"`
+	Roles     []models.Role `json:"roles"`
+	SessionID string        `json:"sid"`
 }
 
 type claims struct {
 	jwt.RegisteredClaims
-	User UserContext `json:"user"`
+	User    UserContext `json:"user"`
+	Purpose string      `json:"purpose,omitempty"`
 }
 
 func NewJWTService(secret string, expires time.Duration) *jwtService {
+	key := NewHMACKey("", secret)
 	return &jwtService{
-		secret:       secret,
+		signer:       key,
+		keys:         map[string]*SigningKey{key.ID: key},
+		expiresAfter: expires,
+	}
+}
+
+// NewJWTServiceWithKeys signs tokens with the first key and accepts tokens
+// signed with any of the keys.
+func NewJWTServiceWithKeys(keys []*SigningKey, expires time.Duration) (*jwtService, error) {
+	if len(keys) == 0 || !keys[0].CanSign() {
+		return nil, errs.ErrSigningKeyNotPrivate
+	}
+
+	s := &jwtService{
+		signer:       keys[0],
+		keys:         make(map[string]*SigningKey, len(keys)),
 		expiresAfter: expires,
 	}
+	for _, k := range keys {
+		if _, ok := s.keys[k.ID]; ok {
+			return nil, fmt.Errorf("%w: %s", errs.ErrSigningKeyDuplicate, k.ID)
+		}
+		s.keys[k.ID] = k
+	}
+	return s, nil
 }
 
-func (s *jwtService) GenerateToken(u *models.User) (string, error) {
-	token := jwt.NewWithClaims(signingMethod, claims{
+func (s *jwtService) GenerateToken(u *models.User, sessionID string) (string, error) {
+	return s.sign(claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.expiresAfter)),
 		},
-		User: UserContext{ID: u.ID, Roles: u.Roles},
+		User: UserContext{ID: u.ID, Roles: u.Roles, SessionID: sessionID},
+	})
+}
+
+func (s *jwtService) GetClaims(tokenString string) (*UserContext, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errs.ErrTokenInvalid
+	}
+	return &claims.User, nil
+}
+
+// GenerateChallengeToken issues a short-lived token proving that the user
+// passed the password check and still has to pass two-factor authentication.
+// It can not be used as an access token.
+func (s *jwtService) GenerateChallengeToken(userID string) (string, error) {
+	return s.sign(claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(challengeExpiresAfter)),
+		},
+		Purpose: purposeTwoFactor,
 	})
+}
 
-	tokenString, err := token.SignedString([]byte(s.secret))
+func (s *jwtService) ParseChallengeToken(tokenString string) (string, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if claims.Purpose != purposeTwoFactor || claims.Subject == "" {
+		return "", errs.ErrTokenInvalid
+	}
+	return claims.Subject, nil
+}
+
+func (s *jwtService) sign(c claims) (string, error) {
+	token := jwt.NewWithClaims(s.signer.Method, c)
+	if s.signer.ID != "" {
+		token.Header["kid"] = s.signer.ID
+	}
+
+	tokenString, err := token.SignedString(s.signer.private)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func (s *jwtService) GetClaims(tokenString string) (*UserContext, error) {
+func (s *jwtService) parse(tokenString string) (*claims, error) {
 	claims := &claims{}
 	keyfunc := func(t *jwt.Token) (interface{}, error) {
-		if t.Method != signingMethod {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok || t.Method.Alg() != key.Method.Alg() {
 			return nil, errs.ErrTokenInvalid
 		}
-		return []byte(s.secret), nil
+		return key.public, nil
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, keyfunc)
@@ -77,7 +153,18 @@ func (s *jwtService) GetClaims(tokenString string) (*UserContext, error) {
 		return nil, errs.ErrTokenInvalid
 	}
 
-	return &claims.User, nil
+	return claims, nil
+}
+
+func (s *jwtService) JWKS() *dto.JSONWebKeySet {
+	set := &dto.JSONWebKeySet{Keys: []dto.JSONWebKey{}}
+	for _, k := range s.keys {
+		if jwk, ok := k.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
 }
 
 func ContextWithUser(ctx context.Context, u *UserContext) context.Context {
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func writePrivateKey(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func TestJWTService_AsymmetricKeys(t *testing.T) {
+	dir := t.TempDir()
+	testUser := &models.User{ID: "user123", Roles: []models.Role{models.RoleUser}}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaPath := writePrivateKey(t, dir, "rsa-2024.pem", rsaKey)
+	edPath := writePrivateKey(t, dir, "ed-2025.pem", edKey)
+	rsaPublicPath := writePublicKey(t, dir, "rsa-2024.pub", &rsaKey.PublicKey)
+
+	for _, tt := range []struct {
+		name string
+		path string
+		alg  string
+	}{
+		{name: "RS256", path: rsaPath, alg: "RS256"},
+		{name: "EdDSA", path: edPath, alg: "EdDSA"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadSigningKeys([]string{tt.path})
+			require.NoError(t, err)
+			service, err := NewJWTServiceWithKeys(keys, time.Hour)
+			require.NoError(t, err)
+
+			tokenString, err := service.GenerateToken(testUser, "session123")
+			require.NoError(t, err)
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims{})
+			require.NoError(t, err)
+			require.Equal(t, tt.alg, token.Method.Alg())
+			require.Equal(t, keys[0].ID, token.Header["kid"])
+
+			c, err := service.GetClaims(tokenString)
+			require.NoError(t, err)
+			require.Equal(t, testUser.ID, c.ID)
+		})
+	}
+
+	t.Run("rotation keeps old tokens valid", func(t *testing.T) {
+		oldKeys, err := LoadSigningKeys([]string{rsaPath})
+		require.NoError(t, err)
+		oldService, err := NewJWTServiceWithKeys(oldKeys, time.Hour)
+		require.NoError(t, err)
+		oldToken, err := oldService.GenerateToken(testUser, "session123")
+		require.NoError(t, err)
+
+		newKeys, err := LoadSigningKeys([]string{edPath, rsaPublicPath})
+		require.NoError(t, err)
+		newService, err := NewJWTServiceWithKeys(newKeys, time.Hour)
+		require.NoError(t, err)
+
+		c, err := newService.GetClaims(oldToken)
+		require.NoError(t, err)
+		require.Equal(t, testUser.ID, c.ID)
+
+		newToken, err := newService.GenerateToken(testUser, "session123")
+		require.NoError(t, err)
+		_, err = oldService.GetClaims(newToken)
+		require.ErrorIs(t, err, errs.ErrTokenInvalid)
+	})
+
+	t.Run("algorithm confusion is rejected", func(t *testing.T) {
+		keys, err := LoadSigningKeys([]string{rsaPublicPath})
+		require.NoError(t, err)
+		service := &jwtService{keys: map[string]*SigningKey{keys[0].ID: keys[0]}}
+
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{User: UserContext{ID: "attacker"}})
+		forged.Header["kid"] = keys[0].ID
+		der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		tokenString, err := forged.SignedString(der)
+		require.NoError(t, err)
+
+		_, err = service.GetClaims(tokenString)
+		require.ErrorIs(t, err, errs.ErrTokenInvalid)
+	})
+
+	t.Run("public key can not sign", func(t *testing.T) {
+		keys, err := LoadSigningKeys([]string{rsaPublicPath, rsaPath})
+		require.NoError(t, err)
+		_, err = NewJWTServiceWithKeys(keys, time.Hour)
+		require.ErrorIs(t, err, errs.ErrSigningKeyNotPrivate)
+	})
+
+	t.Run("duplicate kid", func(t *testing.T) {
+		keys, err := LoadSigningKeys([]string{rsaPath, rsaPublicPath})
+		require.NoError(t, err)
+		_, err = NewJWTServiceWithKeys(keys, time.Hour)
+		require.ErrorIs(t, err, errs.ErrSigningKeyDuplicate)
+	})
+
+	t.Run("JWKS publishes public keys only", func(t *testing.T) {
+		keys, err := LoadSigningKeys([]string{edPath, rsaPublicPath})
+		require.NoError(t, err)
+		service, err := NewJWTServiceWithKeys(keys, time.Hour)
+		require.NoError(t, err)
+
+		set := service.JWKS()
+		require.Len(t, set.Keys, 2)
+		require.Equal(t, "ed-2025", set.Keys[0].KeyID)
+		require.Equal(t, "OKP", set.Keys[0].KeyType)
+		require.Equal(t, "Ed25519", set.Keys[0].Curve)
+		require.NotEmpty(t, set.Keys[0].X)
+		require.Equal(t, "rsa-2024", set.Keys[1].KeyID)
+		require.Equal(t, "RSA", set.Keys[1].KeyType)
+		require.Equal(t, "RS256", set.Keys[1].Algorithm)
+		require.Equal(t, "AQAB", set.Keys[1].E)
+
+		require.Empty(t, NewJWTService("secret", time.Hour).JWKS().Keys)
+	})
+
+	t.Run("unsupported pem", func(t *testing.T) {
+		_, err := ParseSigningKey("bad", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}))
+		require.ErrorIs(t, err, errs.ErrSigningKeyUnsupported)
+
+		_, err = ParseSigningKey("bad", []byte("not a pem"))
+		require.ErrorIs(t, err, errs.ErrSigningKeyInvalid)
+	})
+}
@@ -24,7 +24,7 @@ func TestJWTService_GenerateToken_GetClaims(t *testing.T) {
 	}
 
 	t.Run("GenerateToken and GetClaims success", func(t *testing.T) {
-		tokenString, err := service.GenerateToken(testUser)
+		tokenString, err := service.GenerateToken(testUser, "session123")
 		require.NoError(t, err)
 		require.NotEmpty(t, tokenString)
 
@@ -32,12 +32,13 @@ func TestJWTService_GenerateToken_GetClaims(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, testUser.ID, claims.ID)
 		require.EqualValues(t, testUser.Roles, claims.Roles)
+		require.Equal(t, "session123", claims.SessionID)
 	})
 
 	t.Run("GetClaims with expired token", func(t *testing.T) {
 		shortExpireService := NewJWTService(secret, -1*time.Hour)
 
-		tokenString, err := shortExpireService.GenerateToken(testUser)
+		tokenString, err := shortExpireService.GenerateToken(testUser, "session123")
 		require.NoError(t, err)
 
 		claims, err := service.GetClaims(tokenString)
@@ -47,7 +48,7 @@ func TestJWTService_GenerateToken_GetClaims(t *testing.T) {
 	})
 
 	t.Run("GetClaims with invalid signature", func(t *testing.T) {
-		tokenString, err := service.GenerateToken(testUser)
+		tokenString, err := service.GenerateToken(testUser, "session123")
 		require.NoError(t, err)
 
 		wrongSecretService := NewJWTService("wrong-secret-key", expires)
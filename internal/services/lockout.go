@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+const (
+	loginDelayBase     = time.Second
+	loginMaxDelay      = 30 * time.Second
+	loginMaxLockout    = 24 * time.Hour
+	loginFailureWindow = 24 * time.Hour
+)
+
+type LoginAttemptStore interface {
+	Get(ctx context.Context, scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error)
+	RegisterFailure(ctx context.Context, scope models.LoginAttemptScope, key string,
+		now, resetBefore time.Time) (*models.LoginAttempt, error)
+	Lock(ctx context.Context, scope models.LoginAttemptScope, key string, until time.Time) error
+	Reset(ctx context.Context, scope models.LoginAttemptScope, key string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LockoutPolicy sets how many failed logins are tolerated per login and per
+// client IP before the key is locked out for Lockout. Every further failure
+// doubles the lockout.
+type LockoutPolicy struct {
+	MaxLoginFailures int
+	MaxIPFailures    int
+	Lockout          time.Duration
+}
+
+// LoginLockedError is returned while a login or client IP is locked out.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return errs.ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return errs.ErrLoginLocked
+}
+
+type loginGuard struct {
+	store  LoginAttemptStore
+	policy LockoutPolicy
+}
+
+func NewLoginGuard(store LoginAttemptStore, policy LockoutPolicy) *loginGuard {
+	return &loginGuard{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Check fails with *LoginLockedError if either the login or the client IP is
+// currently locked.
+func (g *loginGuard) Check(ctx context.Context, login, clientIP string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+	for scope, key := range g.keys(login, clientIP) {
+		attempt, err := g.store.Get(ctx, scope, key)
+		if err != nil {
+			if errors.Is(err, errs.ErrLoginAttemptsNotFound) {
+				continue
+			}
+			return err
+		}
+		if attempt.Locked(now) {
+			retryAfter = max(retryAfter, attempt.LockedUntil.Sub(now))
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RegisterFailure counts a failed login and locks the login and the client
+// IP for a progressively longer time.
+func (g *loginGuard) RegisterFailure(ctx context.Context, login, clientIP string) error {
+	now := time.Now().UTC()
+	for scope, key := range g.keys(login, clientIP) {
+		attempt, err := g.store.RegisterFailure(ctx, scope, key, now, now.Add(-loginFailureWindow))
+		if err != nil {
+			return err
+		}
+		delay := g.policy.lockDuration(attempt.Failures, g.maxFailures(scope))
+		if delay <= 0 {
+			continue
+		}
+		err = g.store.Lock(ctx, scope, key, now.Add(delay))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset forgets the failures of the login after a successful login. The
+// client IP counter is kept, otherwise a single valid account would let an
+// attacker reset it at will.
+func (g *loginGuard) Reset(ctx context.Context, login string) error {
+	return g.store.Reset(ctx, models.LoginAttemptScopeLogin, login)
+}
+
+func (g *loginGuard) DeleteStale(ctx context.Context) (int64, error) {
+	return g.store.DeleteStale(ctx, time.Now().UTC().Add(-loginFailureWindow))
+}
+
+func (g *loginGuard) keys(login, clientIP string) map[models.LoginAttemptScope]string {
+	keys := map[models.LoginAttemptScope]string{models.LoginAttemptScopeLogin: login}
+	if clientIP != "" {
+		keys[models.LoginAttemptScopeIP] = clientIP
+	}
+	return keys
+}
+
+func (g *loginGuard) maxFailures(scope models.LoginAttemptScope) int {
+	if scope == models.LoginAttemptScopeIP {
+		return g.policy.MaxIPFailures
+	}
+	return g.policy.MaxLoginFailures
+}
+
+// lockDuration returns a short delay growing with every failure below the
+// threshold and a lockout doubling with every failure above it.
+func (p LockoutPolicy) lockDuration(failures, maxFailures int) time.Duration {
+	if maxFailures > 0 && failures >= maxFailures {
+		return doubled(p.Lockout, failures-maxFailures, loginMaxLockout)
+	}
+	if failures < 2 {
+		return 0
+	}
+	return doubled(loginDelayBase, failures-2, min(loginMaxDelay, p.Lockout))
+}
+
+func doubled(base time.Duration, times int, limit time.Duration) time.Duration {
+	d := base
+	for i := 0; i < times && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
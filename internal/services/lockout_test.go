@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoginAttemptStore keeps login attempts in memory.
+type fakeLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[models.LoginAttemptScope]map[string]*models.LoginAttempt
+}
+
+func newFakeLoginAttemptStore() *fakeLoginAttemptStore {
+	return &fakeLoginAttemptStore{attempts: map[models.LoginAttemptScope]map[string]*models.LoginAttempt{
+		models.LoginAttemptScopeLogin: {},
+		models.LoginAttemptScopeIP:    {},
+	}}
+}
+
+func (s *fakeLoginAttemptStore) Get(
+	ctx context.Context, scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attempts[scope][key]
+	if !ok {
+		return nil, errs.ErrLoginAttemptsNotFound
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (s *fakeLoginAttemptStore) RegisterFailure(ctx context.Context, scope models.LoginAttemptScope, key string,
+	now, resetBefore time.Time) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attempts[scope][key]
+	if !ok {
+		a = &models.LoginAttempt{Scope: scope, Key: key}
+		s.attempts[scope][key] = a
+	}
+	if a.LastFailureAt.Before(resetBefore) {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailureAt = now
+	copied := *a
+	return &copied, nil
+}
+
+func (s *fakeLoginAttemptStore) Lock(
+	ctx context.Context, scope models.LoginAttemptScope, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[scope][key].LockedUntil = &until
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) Reset(ctx context.Context, scope models.LoginAttemptScope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts[scope], key)
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestLoginGuard(t *testing.T) {
+	policy := LockoutPolicy{MaxLoginFailures: 3, MaxIPFailures: 5, Lockout: 15 * time.Minute}
+	ctx := context.Background()
+
+	t.Run("логин блокируется после порога", func(t *testing.T) {
+		store := newFakeLoginAttemptStore()
+		guard := NewLoginGuard(store, policy)
+
+		require.NoError(t, guard.RegisterFailure(ctx, "testuser", "10.0.0.1"))
+		require.NoError(t, guard.Check(ctx, "testuser", "10.0.0.1"))
+
+		require.NoError(t, guard.RegisterFailure(ctx, "testuser", "10.0.0.1"))
+		var locked *LoginLockedError
+		require.ErrorAs(t, guard.Check(ctx, "testuser", "10.0.0.1"), &locked)
+		require.LessOrEqual(t, locked.RetryAfter, loginDelayBase)
+
+		require.NoError(t, guard.RegisterFailure(ctx, "testuser", "10.0.0.1"))
+		require.ErrorAs(t, guard.Check(ctx, "testuser", "10.0.0.2"), &locked)
+		require.Greater(t, locked.RetryAfter, 14*time.Minute)
+
+		require.NoError(t, guard.Check(ctx, "otheruser", "10.0.0.2"))
+	})
+
+	t.Run("ip блокируется для всех логинов", func(t *testing.T) {
+		store := newFakeLoginAttemptStore()
+		guard := NewLoginGuard(store, policy)
+
+		for _, login := range []string{"a", "b", "c", "d", "e"} {
+			require.NoError(t, guard.RegisterFailure(ctx, login, "10.0.0.1"))
+		}
+		require.ErrorIs(t, guard.Check(ctx, "f", "10.0.0.1"), errs.ErrLoginLocked)
+		require.NoError(t, guard.Check(ctx, "f", "10.0.0.2"))
+	})
+
+	t.Run("сброс после успешного входа", func(t *testing.T) {
+		store := newFakeLoginAttemptStore()
+		guard := NewLoginGuard(store, policy)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, guard.RegisterFailure(ctx, "testuser", "10.0.0.1"))
+		}
+		require.NoError(t, guard.Reset(ctx, "testuser"))
+		require.NoError(t, guard.Check(ctx, "testuser", "10.0.0.2"))
+		_, err := store.Get(ctx, models.LoginAttemptScopeIP, "10.0.0.1")
+		require.NoError(t, err)
+	})
+}
+
+func TestLockoutPolicy_lockDuration(t *testing.T) {
+	policy := LockoutPolicy{Lockout: 15 * time.Minute}
+
+	require.Equal(t, time.Duration(0), policy.lockDuration(1, 5))
+	require.Equal(t, time.Second, policy.lockDuration(2, 5))
+	require.Equal(t, 4*time.Second, policy.lockDuration(4, 5))
+	require.Equal(t, loginMaxDelay, policy.lockDuration(40, 50))
+	require.Equal(t, 15*time.Minute, policy.lockDuration(5, 5))
+	require.Equal(t, 30*time.Minute, policy.lockDuration(6, 5))
+	require.Equal(t, loginMaxLockout, policy.lockDuration(100, 5))
+}
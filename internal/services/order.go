@@ -6,7 +6,9 @@ import (
 	"strconv"
 
 	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/metrics"
 	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/tracing"
 	"github.com/phedde/luhn-algorithm"
 )
 
@@ -27,6 +29,9 @@ func NewOrderService(orderRepository OrderCreator) *orderService {
 }
 
 func (s *orderService) UploadOrder(ctx context.Context, userID, orderNumber string) (*models.Order, error) {
+	ctx, span := tracing.Start(ctx, "orderService.UploadOrder")
+	defer span.End()
+
 	order, err := s.creator.GetByNumber(ctx, orderNumber)
 	if err != nil && !errors.Is(err, errs.ErrOrderNotFound) {
 		return nil, err
@@ -45,6 +50,7 @@ func (s *orderService) UploadOrder(ctx context.Context, userID, orderNumber stri
 	if err != nil {
 		return nil, err
 	}
+	metrics.OrdersUploaded.Inc()
 	return newOrder, nil
 
 }
@@ -58,5 +64,8 @@ func (s *orderService) ValidateOrderNumber(ctx context.Context, orderNumber stri
 }
 
 func (s *orderService) GetUserOrders(ctx context.Context, userID string) ([]*models.Order, error) {
+	ctx, span := tracing.Start(ctx, "orderService.GetUserOrders")
+	defer span.End()
+
 	return s.creator.GetUserOrders(ctx, userID)
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/tracing"
+)
+
+type PasswordUpdater interface {
+	UserGetter
+	UpdatePassword(ctx context.Context, userID, passwordHash string, at time.Time) error
+}
+
+type passwordService struct {
+	users    PasswordUpdater
+	policy   PasswordPolicy
+	hasher   PasswordHasher
+	sessions SessionServiceInterface
+	guard    LoginGuardInterface
+}
+
+func NewPasswordService(
+	users PasswordUpdater,
+	policy PasswordPolicy,
+	hasher PasswordHasher,
+	sessions SessionServiceInterface,
+	guard LoginGuardInterface) *passwordService {
+
+	return &passwordService{
+		users:    users,
+		policy:   policy,
+		hasher:   hasher,
+		sessions: sessions,
+		guard:    guard,
+	}
+}
+
+// ChangePassword replaces the password of the user and revokes all of the
+// user sessions. A new session is started for the caller, so only the
+// device that changed the password stays logged in. Wrong old passwords count
+// as failed logins.
+func (s *passwordService) ChangePassword(
+	ctx context.Context, userID, clientIP string, req *dto.ChangePasswordRequest) (*Tokens, error) {
+
+	ctx, span := tracing.Start(ctx, "passwordService.ChangePassword")
+	defer span.End()
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.guard.Check(ctx, u.Login, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.hasher.Verify(u.PasswordHash, req.OldPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		err = s.guard.RegisterFailure(ctx, u.Login, clientIP)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errs.ErrWrongPassword
+	}
+
+	if req.NewPassword == req.OldPassword {
+		return nil, errs.ErrPasswordUnchanged
+	}
+	err = s.policy.ValidatePassword(u.Login, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	u.PasswordHash, err = s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.UpdatePassword(ctx, u.ID, u.PasswordHash, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sessions.Start(ctx, u)
+}
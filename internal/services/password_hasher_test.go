@@ -0,0 +1,88 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(1024, 2, 1)
+
+	hash, err := hasher.Hash("secret-password")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=2,p=1$"))
+	require.True(t, hasher.Supports(hash))
+
+	ok, err := hasher.Verify(hash, "secret-password")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = hasher.Verify(hash, "wrong-password")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	other, err := hasher.Hash("secret-password")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other)
+
+	require.False(t, hasher.NeedsRehash(hash))
+	require.True(t, NewArgon2idHasher(2048, 2, 1).NeedsRehash(hash))
+	require.True(t, NewArgon2idHasher(1024, 3, 1).NeedsRehash(hash))
+
+	for _, malformed := range []string{
+		"$argon2id$v=19$m=1024,t=2,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=2,p=0$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=2,p=1$!!!$a2V5",
+	} {
+		_, err = hasher.Verify(malformed, "secret-password")
+		require.ErrorIs(t, err, errs.ErrPasswordHashInvalid, malformed)
+	}
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	hash, err := hasher.Hash("secret-password")
+	require.NoError(t, err)
+	require.True(t, hasher.Supports(hash))
+
+	ok, err := hasher.Verify(hash, "secret-password")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = hasher.Verify(hash, "wrong-password")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.False(t, hasher.NeedsRehash(hash))
+	require.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hash))
+}
+
+func TestPasswordHasher(t *testing.T) {
+	argon2id := NewArgon2idHasher(1024, 1, 1)
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	hasher := NewPasswordHasher(argon2id, bcryptHasher)
+
+	legacy, err := bcryptHasher.Hash("secret-password")
+	require.NoError(t, err)
+	ok, err := hasher.Verify(legacy, "secret-password")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, hasher.NeedsRehash(legacy))
+
+	current, err := hasher.Hash("secret-password")
+	require.NoError(t, err)
+	require.True(t, argon2id.Supports(current))
+	require.False(t, hasher.NeedsRehash(current))
+
+	_, err = hasher.Verify("$md5$abc", "secret-password")
+	require.ErrorIs(t, err, errs.ErrPasswordHashUnknown)
+
+	_, err = NewPasswordHasher(bcryptHasher).Verify(current, "secret-password")
+	require.ErrorIs(t, err, errs.ErrPasswordHashUnknown)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Soliard/gophermart/internal/errs"
+)
+
+// maxPasswordBytes bounds passwords whatever the hash algorithm. It is the
+// most bcrypt can hash, so a password stays usable after PASSWORD_HASH is
+// switched from argon2id to bcrypt.
+const maxPasswordBytes = 72
+
+//go:embed passwords/common.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9._@+-]{3,64}$`)
+
+// PasswordPolicy requires passwords of at least MinLength characters using
+// at least MinClasses of lower case letters, upper case letters, digits and
+// other symbols. Well-known passwords are always rejected.
+type PasswordPolicy struct {
+	MinLength  int
+	MinClasses int
+}
+
+func (p PasswordPolicy) ValidateLogin(login string) error {
+	if !loginPattern.MatchString(login) {
+		return errs.ErrLoginFormatInvalid
+	}
+	return nil
+}
+
+func (p PasswordPolicy) ValidatePassword(login, password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errs.ErrPasswordTooShort
+	}
+	if len(password) > maxPasswordBytes {
+		return errs.ErrPasswordTooLong
+	}
+	if passwordClasses(password) < p.MinClasses {
+		return errs.ErrPasswordTooWeak
+	}
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok || strings.EqualFold(password, login) {
+		return errs.ErrPasswordTooCommon
+	}
+	return nil
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			classes++
+		}
+	}
+	return classes
+}
+
+func parseCommonPasswords(data string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_ValidatePassword(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 3}
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		expected error
+	}{
+		{name: "надежный пароль", login: "user1", password: "Correct-horse", expected: nil},
+		{name: "юникод", login: "user1", password: "Пароль-2024", expected: nil},
+		{name: "короткий", login: "user1", password: "Ab1-", expected: errs.ErrPasswordTooShort},
+		{name: "длина в символах, а не байтах", login: "user1", password: "Пар1", expected: errs.ErrPasswordTooShort},
+		{name: "слишком длинный", login: "user1", password: strings.Repeat("Ab1-", 19), expected: errs.ErrPasswordTooLong},
+		{name: "мало классов символов", login: "user1", password: "correcthorse42", expected: errs.ErrPasswordTooWeak},
+		{name: "распространенный пароль", login: "user1", password: "P@ssw0rd", expected: errs.ErrPasswordTooCommon},
+		{name: "совпадает с логином", login: "Admin-2024", password: "admin-2024", expected: errs.ErrPasswordTooCommon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.ValidatePassword(tt.login, tt.password)
+			if tt.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_ValidateLogin(t *testing.T) {
+	policy := PasswordPolicy{}
+
+	for _, login := range []string{"user1", "john.doe@example.com", "a_b-c+d"} {
+		require.NoError(t, policy.ValidateLogin(login), login)
+	}
+	for _, login := range []string{"ab", "user 1", "юзер", strings.Repeat("a", 65), "user;drop"} {
+		require.ErrorIs(t, policy.ValidateLogin(login), errs.ErrLoginFormatInvalid, login)
+	}
+}
+
+func TestCommonPasswordsLoaded(t *testing.T) {
+	require.Greater(t, len(commonPasswords), 100)
+	require.Contains(t, commonPasswords, "password")
+	require.NotContains(t, commonPasswords, "")
+}
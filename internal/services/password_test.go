@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockPasswordUpdater struct {
+	mockUserGetter
+}
+
+func (m *mockPasswordUpdater) UpdatePassword(ctx context.Context, userID, passwordHash string, at time.Time) error {
+	args := m.Called(ctx, userID, passwordHash, at)
+	return args.Error(0)
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	oldPassword := "Old-password-1"
+	newPassword := "New-password-2"
+	hash, _ := bcrypt.GenerateFromPassword([]byte(oldPassword), bcrypt.MinCost)
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 2}
+
+	newUser := func() *models.User {
+		return &models.User{ID: "user123", Login: "testuser", PasswordHash: string(hash)}
+	}
+
+	tests := []struct {
+		name          string
+		req           *dto.ChangePasswordRequest
+		mockSetup     func(*mockPasswordUpdater, *mockSessionService, *mockLoginGuard)
+		expectedError error
+	}{
+		{
+			name: "успешная смена пароля",
+			req:  &dto.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: newPassword},
+			mockSetup: func(mu *mockPasswordUpdater, ms *mockSessionService, mg *mockLoginGuard) {
+				mu.On("GetByID", mock.Anything, "user123").Return(newUser(), nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+				mu.On("UpdatePassword", mock.Anything, "user123", mock.MatchedBy(func(h string) bool {
+					return bcrypt.CompareHashAndPassword([]byte(h), []byte(newPassword)) == nil
+				}), mock.Anything).Return(nil)
+				ms.On("Start", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+					return u.ID == "user123"
+				})).Return(&Tokens{Access: "a", Refresh: "r"}, nil)
+			},
+		},
+		{
+			name: "неверный старый пароль",
+			req:  &dto.ChangePasswordRequest{OldPassword: "wrong", NewPassword: newPassword},
+			mockSetup: func(mu *mockPasswordUpdater, ms *mockSessionService, mg *mockLoginGuard) {
+				mu.On("GetByID", mock.Anything, "user123").Return(newUser(), nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+				mg.On("RegisterFailure", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+			},
+			expectedError: errs.ErrWrongPassword,
+		},
+		{
+			name: "попытки заблокированы",
+			req:  &dto.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: newPassword},
+			mockSetup: func(mu *mockPasswordUpdater, ms *mockSessionService, mg *mockLoginGuard) {
+				mu.On("GetByID", mock.Anything, "user123").Return(newUser(), nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").
+					Return(&LoginLockedError{RetryAfter: time.Minute})
+			},
+			expectedError: errs.ErrLoginLocked,
+		},
+		{
+			name: "тот же пароль",
+			req:  &dto.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: oldPassword},
+			mockSetup: func(mu *mockPasswordUpdater, ms *mockSessionService, mg *mockLoginGuard) {
+				mu.On("GetByID", mock.Anything, "user123").Return(newUser(), nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+			},
+			expectedError: errs.ErrPasswordUnchanged,
+		},
+		{
+			name: "новый пароль не соответствует политике",
+			req:  &dto.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: "short"},
+			mockSetup: func(mu *mockPasswordUpdater, ms *mockSessionService, mg *mockLoginGuard) {
+				mu.On("GetByID", mock.Anything, "user123").Return(newUser(), nil)
+				mg.On("Check", mock.Anything, "testuser", "10.0.0.1").Return(nil)
+			},
+			expectedError: errs.ErrPasswordTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(mockPasswordUpdater)
+			sessions := new(mockSessionService)
+			guard := new(mockLoginGuard)
+			tt.mockSetup(users, sessions, guard)
+
+			service := NewPasswordService(users, policy, NewPasswordHasher(NewBcryptHasher(bcrypt.MinCost)), sessions, guard)
+			tokens, err := service.ChangePassword(context.Background(), "user123", "10.0.0.1", tt.req)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				require.Nil(t, tokens)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, &Tokens{Access: "a", Refresh: "r"}, tokens)
+			}
+			users.AssertExpectations(t)
+			sessions.AssertExpectations(t)
+			guard.AssertExpectations(t)
+		})
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/Soliard/gophermart/internal/dto"
 	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/Soliard/gophermart/internal/tracing"
 )
 
 type UserRegistrator interface {
@@ -16,18 +16,33 @@ type UserRegistrator interface {
 
 type registrationService struct {
 	registrator UserRegistrator
+	policy      PasswordPolicy
+	hasher      PasswordHasher
 }
 
-func NewRegistrationService(userRepo UserRegistrator) *registrationService {
+func NewRegistrationService(userRepo UserRegistrator, policy PasswordPolicy, hasher PasswordHasher) *registrationService {
 	return &registrationService{
 		registrator: userRepo,
+		policy:      policy,
+		hasher:      hasher,
 	}
 }
 
 func (s *registrationService) Register(ctx context.Context, req *dto.RegisterRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "registrationService.Register")
+	defer span.End()
+
 	if req.Login == "" || req.Password == "" {
 		return nil, errs.ErrEmptyLoginOrPassword
 	}
+	err := s.policy.ValidateLogin(req.Login)
+	if err != nil {
+		return nil, err
+	}
+	err = s.policy.ValidatePassword(req.Login, req.Password)
+	if err != nil {
+		return nil, err
+	}
 
 	exists, err := s.registrator.UserExists(ctx, req.Login)
 	if err != nil {
@@ -37,12 +52,12 @@ func (s *registrationService) Register(ctx context.Context, req *dto.RegisterReq
 		return nil, errs.ErrUserAlreadyExists
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	user := models.NewUser(req.Login, string(hashedPassword))
+	user := models.NewUser(req.Login, hashedPassword)
 
 	err = s.registrator.Create(ctx, user)
 	if err != nil {
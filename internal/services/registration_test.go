@@ -9,6 +9,7 @@ import (
 	"github.com/Soliard/gophermart/internal/models"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type mockUserRegistrator struct {
@@ -34,30 +35,43 @@ func Test_registrationService_Register(t *testing.T) {
 	}{
 		{
 			name:          "новый юзер",
-			regReq:        dto.RegisterRequest{Login: "u1", Password: "123"},
+			regReq:        dto.RegisterRequest{Login: "user1", Password: "correct-horse-42"},
 			expectedError: nil,
 			mockSetup: func(m *mockUserRegistrator) {
-				m.On("UserExists", mock.Anything, "u1").Return(false, nil)
+				m.On("UserExists", mock.Anything, "user1").Return(false, nil)
 				m.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
-					return u.Login == "u1"
+					return u.Login == "user1"
 				})).Return(nil)
 			},
 		},
 		{
 			name:          "юзер уже существует",
-			regReq:        dto.RegisterRequest{Login: "u2", Password: "asdf"},
+			regReq:        dto.RegisterRequest{Login: "user2", Password: "correct-horse-42"},
 			expectedError: errs.ErrUserAlreadyExists,
 			mockSetup: func(mur *mockUserRegistrator) {
-				mur.On("UserExists", mock.Anything, "u2").Return(true, nil)
+				mur.On("UserExists", mock.Anything, "user2").Return(true, nil)
 			},
 		},
+		{
+			name:          "неверный формат логина",
+			regReq:        dto.RegisterRequest{Login: "user 3", Password: "correct-horse-42"},
+			expectedError: errs.ErrLoginFormatInvalid,
+			mockSetup:     func(mur *mockUserRegistrator) {},
+		},
+		{
+			name:          "слабый пароль",
+			regReq:        dto.RegisterRequest{Login: "user4", Password: "123"},
+			expectedError: errs.ErrPasswordTooShort,
+			mockSetup:     func(mur *mockUserRegistrator) {},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := new(mockUserRegistrator)
 			tt.mockSetup(mock)
 			ctx := context.Background()
-			service := NewRegistrationService(mock)
+			service := NewRegistrationService(mock, PasswordPolicy{MinLength: 8, MinClasses: 2},
+				NewPasswordHasher(NewBcryptHasher(bcrypt.MinCost)))
 			user, err := service.Register(ctx, &tt.regReq)
 
 			if err == nil {
@@ -1,19 +1,25 @@
 package services
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Soliard/gophermart/internal/config"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/metrics"
 )
 
 type UserRepository interface {
 	UserLoginner
 	UserRegistrator
+	PasswordUpdater
 }
 
 type OrderRepository interface {
 	OrderCreator
 	AccrualUpdater
+	metrics.OrderStatusCounter
 }
 
 type WithdrawRepository interface {
@@ -24,29 +30,130 @@ type BalanceRepository interface {
 	BalanceProvider
 }
 
-type Services struct {
-	Auth       AuthServiceInterface
-	Reg        RegistrationServiceInterface
-	JWT        JWTServiceInterface
-	Order      OrderServiceInterface
-	Accrual    AccrualServiceInterface
-	Withdrawal WithdrawalServiceInterface
-	Balance    BalanceServiceInterface
+type IdempotencyRepository interface {
+	IdempotencyStore
+}
+
+type SessionRepository interface {
+	SessionStore
+}
+
+type AdminRepository interface {
+	AdminStore
+}
+
+type LoginAttemptRepository interface {
+	LoginAttemptStore
+}
+
+type TwoFactorRepository interface {
+	TwoFactorStore
+}
+
+type Repositories struct {
+	Users         UserRepository
+	Orders        OrderRepository
+	Withdrawals   WithdrawRepository
+	Balance       BalanceRepository
+	Idempotency   IdempotencyRepository
+	Sessions      SessionRepository
+	Admin         AdminRepository
+	LoginAttempts LoginAttemptRepository
+	TwoFactor     TwoFactorRepository
 }
 
-func New(
-	users UserRepository, orders OrderRepository,
-	withdrawals WithdrawRepository, balance BalanceRepository,
-	c *config.Config) *Services {
+type Services struct {
+	Auth        AuthServiceInterface
+	Reg         RegistrationServiceInterface
+	JWT         JWTServiceInterface
+	Session     SessionServiceInterface
+	LoginGuard  LoginGuardInterface
+	TwoFactor   TwoFactorServiceInterface
+	Password    PasswordServiceInterface
+	Order       OrderServiceInterface
+	Accrual     AccrualServiceInterface
+	Withdrawal  WithdrawalServiceInterface
+	Balance     BalanceServiceInterface
+	Idempotency IdempotencyServiceInterface
+	Admin       AdminServiceInterface
+	Health      HealthServiceInterface
+}
 
+func New(repos *Repositories, c *config.Config) (*Services, error) {
+	var err error
 	services := &Services{}
-	services.JWT = NewJWTService(c.TokenSecret, time.Duration(c.TokenExpMinutes)*time.Minute)
-	services.Balance = NewBalanceService(balance)
-	services.Auth = NewAuthService(users, services.JWT)
-	services.Reg = NewRegistrationService(users)
-	services.Order = NewOrderService(orders)
-	services.Accrual = NewAccrualService(orders, c.AccrualAddress)
-	services.Withdrawal = NewWithdrawalService(withdrawals, services.Balance, services.Order)
-
-	return services
+	services.JWT, err = newJWTServiceFromConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	services.Balance = NewBalanceService(repos.Balance)
+	services.Session = NewSessionService(repos.Sessions, repos.Users, services.JWT,
+		time.Duration(c.RefreshExpHours)*time.Hour)
+	services.LoginGuard = NewLoginGuard(repos.LoginAttempts, LockoutPolicy{
+		MaxLoginFailures: c.LoginMaxAttempts,
+		MaxIPFailures:    c.LoginIPMaxAttempts,
+		Lockout:          time.Duration(c.LoginLockoutMinutes) * time.Minute,
+	})
+	hasher, err := newPasswordHasherFromConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	services.TwoFactor = NewTwoFactorService(repos.TwoFactor, repos.Users)
+	services.Auth = NewAuthService(repos.Users, services.Session, services.LoginGuard, hasher,
+		services.JWT, services.TwoFactor)
+	policy := PasswordPolicy{MinLength: c.PasswordMinLength, MinClasses: c.PasswordMinClasses}
+	services.Reg = NewRegistrationService(repos.Users, policy, hasher)
+	services.Password = NewPasswordService(repos.Users, policy, hasher, services.Session, services.LoginGuard)
+	services.Order = NewOrderService(repos.Orders)
+	services.Accrual = NewAccrualService(repos.Orders, c.AccrualAddress, AccrualConfig{
+		Workers:   c.AccrualWorkers,
+		BatchSize: c.AccrualBatchSize,
+		Timeout:   time.Duration(c.AccrualTimeoutSeconds) * time.Second,
+		Retry: RetryConfig{
+			MaxRetries: c.AccrualMaxRetries,
+			RetryDelay: time.Duration(c.AccrualRetryDelaySeconds) * time.Second,
+		},
+	})
+	services.Withdrawal = NewWithdrawalService(repos.Withdrawals, services.Order)
+	services.Idempotency = NewIdempotencyService(repos.Idempotency, time.Duration(c.IdempotencyTTLHours)*time.Hour)
+	services.Admin = NewAdminService(repos.Admin, repos.Users, repos.Orders, repos.Withdrawals, services.Session)
+
+	return services, nil
+}
+
+// newPasswordHasherFromConfig hashes new passwords with the configured
+// algorithm and still accepts hashes made with the other one.
+func newPasswordHasherFromConfig(c *config.Config) (PasswordHasher, error) {
+	bcryptHasher := NewBcryptHasher(c.BcryptCost)
+	argon2idHasher := NewArgon2idHasher(
+		uint32(c.Argon2MemoryKiB), uint32(c.Argon2Iterations), uint8(c.Argon2Parallelism))
+
+	switch c.PasswordHash {
+	case PasswordHashBcrypt:
+		return NewPasswordHasher(bcryptHasher, argon2idHasher), nil
+	case PasswordHashArgon2id:
+		return NewPasswordHasher(argon2idHasher, bcryptHasher), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errs.ErrPasswordHashAlgorithmUnknown, c.PasswordHash)
+	}
+}
+
+// newJWTServiceFromConfig falls back to HS256 with TokenSecret when no key files are configured.
+func newJWTServiceFromConfig(c *config.Config) (JWTServiceInterface, error) {
+	expires := time.Duration(c.TokenExpMinutes) * time.Minute
+	if c.TokenKeyFiles == "" {
+		return NewJWTService(c.TokenSecret, expires), nil
+	}
+
+	var paths []string
+	for _, p := range strings.Split(c.TokenKeyFiles, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	keys, err := LoadSigningKeys(paths)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTServiceWithKeys(keys, expires)
 }
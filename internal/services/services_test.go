@@ -0,0 +1,15 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
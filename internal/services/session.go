@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+const refreshTokenBytes = 32
+
+type Tokens struct {
+	Access  string
+	Refresh string
+}
+
+type SessionStore interface {
+	Create(ctx context.Context, s *models.Session) error
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	Rotate(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID string, at time.Time) error
+}
+
+type UserGetter interface {
+	GetByID(ctx context.Context, id string) (*models.User, error)
+}
+
+type sessionService struct {
+	store SessionStore
+	users UserGetter
+	jwt   JWTServiceInterface
+	ttl   time.Duration
+}
+
+func NewSessionService(store SessionStore, users UserGetter, jwt JWTServiceInterface, ttl time.Duration) *sessionService {
+	return &sessionService{
+		store: store,
+		users: users,
+		jwt:   jwt,
+		ttl:   ttl,
+	}
+}
+
+// Start opens a new session for an authenticated user and issues its tokens.
+func (s *sessionService) Start(ctx context.Context, u *models.User) (*Tokens, error) {
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := models.NewSession(u.ID, hashRefreshToken(refresh), s.ttl)
+	err = s.store.Create(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := s.jwt.GenerateToken(u, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{Access: access, Refresh: refresh}, nil
+}
+
+// Refresh issues a new access token and rotates the refresh token, so every
+// refresh token can be used only once.
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	oldHash := hashRefreshToken(refreshToken)
+	session, err := s.store.GetByRefreshTokenHash(ctx, oldHash)
+	if err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) {
+			return nil, errs.ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if !session.Active(time.Now()) {
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+
+	u, err := s.users.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Blocked() {
+		return nil, errs.ErrRefreshTokenInvalid
+	}
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.store.Rotate(ctx, session.ID, oldHash, hashRefreshToken(refresh), time.Now().UTC().Add(s.ttl))
+	if err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) {
+			return nil, errs.ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	access, err := s.jwt.GenerateToken(u, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{Access: access, Refresh: refresh}, nil
+}
+
+func (s *sessionService) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	session, err := s.store.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return session.Active(time.Now()), nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, sessionID string) error {
+	return s.store.Revoke(ctx, sessionID, time.Now().UTC())
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID string) error {
+	return s.store.RevokeAllForUser(ctx, userID, time.Now().UTC())
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
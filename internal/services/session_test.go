@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSessionStore struct {
+	mock.Mock
+}
+
+type mockUserGetter struct {
+	mock.Mock
+}
+
+func (m *mockSessionStore) Create(ctx context.Context, s *models.Session) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *mockSessionStore) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Session), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSessionStore) GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	args := m.Called(ctx, hash)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Session), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSessionStore) Rotate(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, id, oldHash, newHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *mockSessionStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *mockSessionStore) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
+func (m *mockUserGetter) GetByID(ctx context.Context, id string) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if v := args.Get(0); v != nil {
+		return v.(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestSessionService_Start(t *testing.T) {
+	testUser := &models.User{ID: "user123", Roles: []models.Role{models.RoleUser}}
+
+	store := new(mockSessionStore)
+	jwt := new(mockJWTService)
+	var created *models.Session
+	store.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+		created = s
+		return s.UserID == "user123" && s.RefreshTokenHash != ""
+	})).Return(nil)
+	jwt.On("GenerateToken", testUser, mock.Anything).Return("access-token", nil)
+
+	service := NewSessionService(store, new(mockUserGetter), jwt, time.Hour)
+	tokens, err := service.Start(context.Background(), testUser)
+	require.NoError(t, err)
+	require.Equal(t, "access-token", tokens.Access)
+	require.NotEmpty(t, tokens.Refresh)
+	require.Equal(t, hashRefreshToken(tokens.Refresh), created.RefreshTokenHash)
+	require.NotEqual(t, tokens.Refresh, created.RefreshTokenHash)
+	jwt.AssertCalled(t, "GenerateToken", testUser, created.ID)
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	testUser := &models.User{ID: "user123", Roles: []models.Role{models.RoleUser}}
+	refresh := "refresh-token"
+	hash := hashRefreshToken(refresh)
+	revokedAt := time.Now().Add(-time.Minute)
+
+	active := &models.Session{ID: "s1", UserID: "user123", ExpiresAt: time.Now().Add(time.Hour)}
+	expired := &models.Session{ID: "s1", UserID: "user123", ExpiresAt: time.Now().Add(-time.Hour)}
+	revoked := &models.Session{ID: "s1", UserID: "user123", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+
+	tests := []struct {
+		name          string
+		mockSetup     func(*mockSessionStore, *mockUserGetter, *mockJWTService)
+		expectedError error
+	}{
+		{
+			name: "успешное обновление с ротацией",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(active, nil)
+				mu.On("GetByID", mock.Anything, "user123").Return(testUser, nil)
+				ms.On("Rotate", mock.Anything, "s1", hash, mock.MatchedBy(func(newHash string) bool {
+					return newHash != hash
+				}), mock.Anything).Return(nil)
+				mj.On("GenerateToken", testUser, "s1").Return("new-access", nil)
+			},
+		},
+		{
+			name: "неизвестный токен",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(nil, errs.ErrSessionNotFound)
+			},
+			expectedError: errs.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "сессия истекла",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(expired, nil)
+			},
+			expectedError: errs.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "сессия отозвана",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(revoked, nil)
+			},
+			expectedError: errs.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "пользователь заблокирован",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				blockedAt := time.Now()
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(active, nil)
+				mu.On("GetByID", mock.Anything, "user123").
+					Return(&models.User{ID: "user123", BlockedAt: &blockedAt}, nil)
+			},
+			expectedError: errs.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "токен уже использован параллельным запросом",
+			mockSetup: func(ms *mockSessionStore, mu *mockUserGetter, mj *mockJWTService) {
+				ms.On("GetByRefreshTokenHash", mock.Anything, hash).Return(active, nil)
+				mu.On("GetByID", mock.Anything, "user123").Return(testUser, nil)
+				ms.On("Rotate", mock.Anything, "s1", hash, mock.Anything, mock.Anything).Return(errs.ErrSessionNotFound)
+			},
+			expectedError: errs.ErrRefreshTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockSessionStore)
+			users := new(mockUserGetter)
+			jwt := new(mockJWTService)
+			tt.mockSetup(store, users, jwt)
+
+			service := NewSessionService(store, users, jwt, time.Hour)
+			tokens, err := service.Refresh(context.Background(), refresh)
+
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				require.Nil(t, tokens)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "new-access", tokens.Access)
+				require.NotEqual(t, refresh, tokens.Refresh)
+			}
+			store.AssertExpectations(t)
+			users.AssertExpectations(t)
+			jwt.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionService_IsActive(t *testing.T) {
+	revokedAt := time.Now()
+	tests := []struct {
+		name      string
+		sessionID string
+		mockSetup func(*mockSessionStore)
+		expected  bool
+	}{
+		{
+			name:      "активная сессия",
+			sessionID: "s1",
+			mockSetup: func(m *mockSessionStore) {
+				m.On("GetByID", mock.Anything, "s1").
+					Return(&models.Session{ID: "s1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+			},
+			expected: true,
+		},
+		{
+			name:      "отозванная сессия",
+			sessionID: "s1",
+			mockSetup: func(m *mockSessionStore) {
+				m.On("GetByID", mock.Anything, "s1").
+					Return(&models.Session{ID: "s1", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+			},
+			expected: false,
+		},
+		{
+			name:      "несуществующая сессия",
+			sessionID: "s1",
+			mockSetup: func(m *mockSessionStore) {
+				m.On("GetByID", mock.Anything, "s1").Return(nil, errs.ErrSessionNotFound)
+			},
+			expected: false,
+		},
+		{
+			name:      "токен без сессии",
+			sessionID: "",
+			mockSetup: func(m *mockSessionStore) {},
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockSessionStore)
+			tt.mockSetup(store)
+
+			service := NewSessionService(store, new(mockUserGetter), new(mockJWTService), time.Hour)
+			active, err := service.IsActive(context.Background(), tt.sessionID)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, active)
+			store.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Тестовые векторы SHA1 из RFC 6238, приложение B.
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		name     string
+		unix     int64
+		expected string
+	}{
+		{name: "T=59", unix: 59, expected: "94287082"},
+		{name: "T=1111111109", unix: 1111111109, expected: "07081804"},
+		{name: "T=1234567890", unix: 1234567890, expected: "89005924"},
+		{name: "T=20000000000", unix: 20000000000, expected: "65353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, totpCode(secret, totpStep(time.Unix(tt.unix, 0)), 8))
+		})
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(59, 0)
+
+	step, ok := matchTOTP(secret, "287082", now)
+	require.True(t, ok)
+	require.Equal(t, int64(1), step)
+
+	_, ok = matchTOTP(secret, "287082", now.Add(3*totpPeriod*time.Second))
+	require.False(t, ok, "код за пределами допустимого сдвига")
+
+	_, ok = matchTOTP(secret, "28708", now)
+	require.False(t, ok, "код неверной длины")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+
+	require.Equal(t, hashRecoveryCode("abcd-efgh"), hashRecoveryCode(" ABCDEFGH "))
+}
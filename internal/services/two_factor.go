@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/dto"
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type TwoFactorStore interface {
+	SetPendingSecret(ctx context.Context, userID, secret string) error
+	Enable(ctx context.Context, userID string, at time.Time, step int64, recoveryCodeHashes []string) error
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) (bool, error)
+}
+
+// TwoFactorRequiredError is returned by Login when the password was correct
+// but the user has to complete the login with a TOTP or recovery code.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return errs.ErrTwoFactorRequired.Error()
+}
+
+func (e *TwoFactorRequiredError) Unwrap() error {
+	return errs.ErrTwoFactorRequired
+}
+
+type twoFactorService struct {
+	store TwoFactorStore
+	users UserGetter
+}
+
+func NewTwoFactorService(store TwoFactorStore, users UserGetter) *twoFactorService {
+	return &twoFactorService{
+		store: store,
+		users: users,
+	}
+}
+
+// Enroll generates a new TOTP secret. It is not required on login until
+// Confirm proves that the authenticator app was set up.
+func (s *twoFactorService) Enroll(ctx context.Context, userID string) (*dto.TwoFactorEnrollment, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TwoFactorEnabled() {
+		return nil, errs.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.store.SetPendingSecret(ctx, u.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollment{Secret: secret, URI: totpURI(u.Login, secret)}, nil
+}
+
+// Confirm enables two-factor authentication and returns the recovery codes.
+// They are stored hashed and can not be shown again.
+func (s *twoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TwoFactorEnabled() {
+		return nil, errs.ErrTwoFactorAlreadyEnabled
+	}
+	if u.TOTPSecret == nil {
+		return nil, errs.ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := matchTOTP(*u.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errs.ErrTwoFactorCodeInvalid
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+
+	err = s.store.Enable(ctx, u.ID, time.Now().UTC(), step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts a TOTP code that was not used before or an unused recovery code.
+func (s *twoFactorService) Verify(ctx context.Context, u *models.User, code string) error {
+	if !u.TwoFactorEnabled() {
+		return errs.ErrTwoFactorNotEnrolled
+	}
+
+	if step, ok := matchTOTP(*u.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.store.UseStep(ctx, u.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errs.ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, u.ID, hashRecoveryCode(code), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errs.ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTwoFactorStore struct {
+	mock.Mock
+}
+
+func (m *mockTwoFactorStore) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorStore) Enable(
+	ctx context.Context, userID string, at time.Time, step int64, recoveryCodeHashes []string) error {
+
+	args := m.Called(ctx, userID, at, step, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorStore) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockTwoFactorStore) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) (bool, error) {
+	args := m.Called(ctx, userID, codeHash, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func currentTOTP(t *testing.T, secret string) string {
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, totpStep(time.Now()), totpDigits)
+}
+
+func TestTwoFactorService_Enroll(t *testing.T) {
+	t.Run("новый секрет", func(t *testing.T) {
+		store := new(mockTwoFactorStore)
+		users := new(mockUserGetter)
+		users.On("GetByID", mock.Anything, "user123").Return(&models.User{ID: "user123", Login: "testuser"}, nil)
+		store.On("SetPendingSecret", mock.Anything, "user123", mock.Anything).Return(nil)
+
+		enrollment, err := NewTwoFactorService(store, users).Enroll(context.Background(), "user123")
+		require.NoError(t, err)
+		require.Len(t, enrollment.Secret, 32)
+		require.Contains(t, enrollment.URI, "otpauth://totp/Gophermart:testuser?")
+		require.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("уже включена", func(t *testing.T) {
+		secret := "SECRET"
+		enabledAt := time.Now()
+		users := new(mockUserGetter)
+		users.On("GetByID", mock.Anything, "user123").
+			Return(&models.User{ID: "user123", TOTPSecret: &secret, TOTPEnabledAt: &enabledAt}, nil)
+
+		_, err := NewTwoFactorService(new(mockTwoFactorStore), users).Enroll(context.Background(), "user123")
+		require.ErrorIs(t, err, errs.ErrTwoFactorAlreadyEnabled)
+	})
+}
+
+func TestTwoFactorService_Confirm(t *testing.T) {
+	secret, err := newTOTPSecret()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		user          *models.User
+		code          string
+		expectEnable  bool
+		expectedError error
+	}{
+		{
+			name:         "верный код включает 2FA",
+			user:         &models.User{ID: "user123", TOTPSecret: &secret},
+			code:         currentTOTP(t, secret),
+			expectEnable: true,
+		},
+		{
+			name:          "неверный код",
+			user:          &models.User{ID: "user123", TOTPSecret: &secret},
+			code:          "abcdef",
+			expectedError: errs.ErrTwoFactorCodeInvalid,
+		},
+		{
+			name:          "подключение не начато",
+			user:          &models.User{ID: "user123"},
+			code:          "123456",
+			expectedError: errs.ErrTwoFactorNotEnrolled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockTwoFactorStore)
+			users := new(mockUserGetter)
+			users.On("GetByID", mock.Anything, "user123").Return(tt.user, nil)
+			if tt.expectEnable {
+				store.On("Enable", mock.Anything, "user123", mock.Anything, mock.Anything,
+					mock.MatchedBy(func(hashes []string) bool { return len(hashes) == recoveryCodeCount })).
+					Return(nil)
+			}
+
+			codes, err := NewTwoFactorService(store, users).Confirm(context.Background(), "user123", tt.code)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				store.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, codes, recoveryCodeCount)
+			store.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTwoFactorService_Verify(t *testing.T) {
+	secret, err := newTOTPSecret()
+	require.NoError(t, err)
+	enabledAt := time.Now()
+	u := &models.User{ID: "user123", TOTPSecret: &secret, TOTPEnabledAt: &enabledAt}
+
+	tests := []struct {
+		name          string
+		code          string
+		mockSetup     func(*mockTwoFactorStore)
+		expectedError error
+	}{
+		{
+			name: "верный TOTP код",
+			code: currentTOTP(t, secret),
+			mockSetup: func(m *mockTwoFactorStore) {
+				m.On("UseStep", mock.Anything, "user123", mock.Anything).Return(true, nil)
+			},
+		},
+		{
+			name: "повторное использование TOTP кода",
+			code: currentTOTP(t, secret),
+			mockSetup: func(m *mockTwoFactorStore) {
+				m.On("UseStep", mock.Anything, "user123", mock.Anything).Return(false, nil)
+			},
+			expectedError: errs.ErrTwoFactorCodeInvalid,
+		},
+		{
+			name: "код восстановления",
+			code: "abcd-efgh",
+			mockSetup: func(m *mockTwoFactorStore) {
+				m.On("UseRecoveryCode", mock.Anything, "user123", hashRecoveryCode("abcd-efgh"), mock.Anything).
+					Return(true, nil)
+			},
+		},
+		{
+			name: "неизвестный код восстановления",
+			code: "zzzz-zzzz",
+			mockSetup: func(m *mockTwoFactorStore) {
+				m.On("UseRecoveryCode", mock.Anything, "user123", mock.Anything, mock.Anything).Return(false, nil)
+			},
+			expectedError: errs.ErrTwoFactorCodeInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(mockTwoFactorStore)
+			tt.mockSetup(store)
+
+			err := NewTwoFactorService(store, new(mockUserGetter)).Verify(context.Background(), u, tt.code)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			store.AssertExpectations(t)
+		})
+	}
+}
@@ -4,60 +4,51 @@ import (
 	"context"
 
 	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/metrics"
 	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/tracing"
 )
 
 type Withdrawer interface {
-	Create(ctx context.Context, w *models.Withdrawal) error
-	WithdrawalExists(ctx context.Context, userID string, orderNumber string) (bool, error)
+	Withdraw(ctx context.Context, w *models.Withdrawal) error
 	GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error)
 }
 
 type withdrawalService struct {
-	repo    Withdrawer
-	balance BalanceServiceInterface
-	orders  OrderServiceInterface
+	repo   Withdrawer
+	orders OrderServiceInterface
 }
 
-func NewWithdrawalService(repo Withdrawer, balance BalanceServiceInterface, orders OrderServiceInterface) *withdrawalService {
+func NewWithdrawalService(repo Withdrawer, orders OrderServiceInterface) *withdrawalService {
 	return &withdrawalService{
-		repo:    repo,
-		balance: balance,
-		orders:  orders,
+		repo:   repo,
+		orders: orders,
 	}
 }
 
-func (s *withdrawalService) ProcessWithdraw(ctx context.Context, userID, orderNumber string, sum float64) error {
+func (s *withdrawalService) ProcessWithdraw(ctx context.Context, userID, orderNumber string, sum models.Points) error {
+	ctx, span := tracing.Start(ctx, "withdrawalService.ProcessWithdraw")
+	defer span.End()
+
 	isValid := s.orders.ValidateOrderNumber(ctx, orderNumber)
 	if !isValid {
 		return errs.ErrOrderIsNotValid
 	}
 
-	exists, err := s.repo.WithdrawalExists(ctx, userID, orderNumber)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errs.ErrWithdrawalAlreadyProcessed
-	}
-
-	balance, err := s.balance.GetBalance(ctx, userID)
-	if err != nil {
-		return err
-	}
-	if balance.Current < sum {
-		return errs.ErrBalanceInsufficient
-	}
-
 	withdrawal := models.NewWithdrawal(userID, orderNumber, sum)
-	err = s.repo.Create(ctx, withdrawal)
+	err := s.repo.Withdraw(ctx, withdrawal)
 	if err != nil {
 		return err
 	}
+	metrics.Withdrawals.Inc()
+	metrics.PointsWithdrawn.Add(sum.Float64())
 
 	return nil
 }
 
 func (s *withdrawalService) GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
+	ctx, span := tracing.Start(ctx, "withdrawalService.GetWithdrawals")
+	defer span.End()
+
 	return s.repo.GetWithdrawals(ctx, userID)
 }
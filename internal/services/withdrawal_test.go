@@ -18,20 +18,11 @@ type mockOrderService struct {
 	mock.Mock
 }
 
-type mockBalanceService struct {
-	mock.Mock
-}
-
-func (m *mockWithdrawer) Create(ctx context.Context, w *models.Withdrawal) error {
+func (m *mockWithdrawer) Withdraw(ctx context.Context, w *models.Withdrawal) error {
 	args := m.Called(ctx, w)
 	return args.Error(0)
 }
 
-func (m *mockWithdrawer) WithdrawalExists(ctx context.Context, userID string, orderNumber string) (bool, error) {
-	args := m.Called(ctx, userID, orderNumber)
-	return args.Bool(0), args.Error(1)
-}
-
 func (m *mockWithdrawer) GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
 	args := m.Called(ctx, userID)
 	if v := args.Get(0); v != nil {
@@ -61,21 +52,13 @@ func (m *mockOrderService) GetUserOrders(ctx context.Context, userID string) ([]
 	return nil, args.Error(1)
 }
 
-func (m *mockBalanceService) GetBalance(ctx context.Context, userID string) (*models.Balance, error) {
-	args := m.Called(ctx, userID)
-	if v := args.Get(0); v != nil {
-		return v.(*models.Balance), args.Error(1)
-	}
-	return nil, args.Error(1)
-}
-
 func Test_withdrawalService_ProcessWithdraw(t *testing.T) {
 	type testCase struct {
 		name          string
 		userID        string
 		order         string
-		sum           float64
-		setupMocks    func(*mockWithdrawer, *mockOrderService, *mockBalanceService)
+		sum           models.Points
+		setupMocks    func(*mockWithdrawer, *mockOrderService)
 		expectedError error
 	}
 
@@ -84,12 +67,12 @@ func Test_withdrawalService_ProcessWithdraw(t *testing.T) {
 			name:   "успешное списание",
 			userID: "user123",
 			order:  "79927398713",
-			sum:    100.50,
-			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService, mbs *mockBalanceService) {
+			sum:    10050,
+			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService) {
 				mos.On("ValidateOrderNumber", mock.Anything, "79927398713").Return(true)
-				mw.On("WithdrawalExists", mock.Anything, "user123", "79927398713").Return(false, nil)
-				mbs.On("GetBalance", mock.Anything, "user123").Return(&models.Balance{Current: 500}, nil)
-				mw.On("Create", mock.Anything, mock.Anything).Return(nil)
+				mw.On("Withdraw", mock.Anything, mock.MatchedBy(func(w *models.Withdrawal) bool {
+					return w.UserID == "user123" && w.OrderNumber == "79927398713" && w.Sum == 10050
+				})).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -97,20 +80,30 @@ func Test_withdrawalService_ProcessWithdraw(t *testing.T) {
 			name:   "недостаточно баллов",
 			userID: "user123",
 			order:  "79927398713",
-			sum:    100.50,
-			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService, mbs *mockBalanceService) {
+			sum:    10050,
+			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService) {
 				mos.On("ValidateOrderNumber", mock.Anything, "79927398713").Return(true)
-				mw.On("WithdrawalExists", mock.Anything, "user123", "79927398713").Return(false, nil)
-				mbs.On("GetBalance", mock.Anything, "user123").Return(&models.Balance{Current: 99}, nil)
+				mw.On("Withdraw", mock.Anything, mock.Anything).Return(errs.ErrBalanceInsufficient)
 			},
 			expectedError: errs.ErrBalanceInsufficient,
 		},
+		{
+			name:   "списание уже обработано",
+			userID: "user123",
+			order:  "79927398713",
+			sum:    10050,
+			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService) {
+				mos.On("ValidateOrderNumber", mock.Anything, "79927398713").Return(true)
+				mw.On("Withdraw", mock.Anything, mock.Anything).Return(errs.ErrWithdrawalAlreadyProcessed)
+			},
+			expectedError: errs.ErrWithdrawalAlreadyProcessed,
+		},
 		{
 			name:   "невалидный номер запроса",
 			userID: "user123",
 			order:  "123",
-			sum:    100.50,
-			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService, mbs *mockBalanceService) {
+			sum:    10050,
+			setupMocks: func(mw *mockWithdrawer, mos *mockOrderService) {
 				mos.On("ValidateOrderNumber", mock.Anything, "123").Return(false)
 			},
 			expectedError: errs.ErrOrderIsNotValid,
@@ -121,22 +114,20 @@ func Test_withdrawalService_ProcessWithdraw(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mw := new(mockWithdrawer)
 			mos := new(mockOrderService)
-			mbs := new(mockBalanceService)
 
-			tt.setupMocks(mw, mos, mbs)
+			tt.setupMocks(mw, mos)
 
-			service := NewWithdrawalService(mw, mbs, mos)
+			service := NewWithdrawalService(mw, mos)
 			err := service.ProcessWithdraw(context.Background(), tt.userID, tt.order, tt.sum)
 
 			if tt.expectedError != nil {
-				require.Error(t, err)
+				require.ErrorIs(t, err, tt.expectedError)
 			} else {
 				require.NoError(t, err)
 			}
 
 			mw.AssertExpectations(t)
 			mos.AssertExpectations(t)
-			mbs.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type AdminRepository struct {
+	s *Storage
+}
+
+func NewAdminRepository(s *Storage) *AdminRepository {
+	return &AdminRepository{s: s}
+}
+
+func (r *AdminRepository) SearchUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var users []*models.User
+	for _, u := range r.s.users {
+		if strings.Contains(strings.ToLower(u.Login), strings.ToLower(login)) {
+			users = append(users, copyUser(u))
+		}
+	}
+	slices.SortFunc(users, func(a, b *models.User) int { return strings.Compare(a.Login, b.Login) })
+	sortByTimeDesc(users, func(u *models.User) time.Time { return u.CreatedAt })
+	return page(users, limit, offset), nil
+}
+
+func (r *AdminRepository) UpdateRoles(
+	ctx context.Context, userID string, roles models.Roles, entry *models.AuditEntry) error {
+
+	return r.withAudit(entry, func() error {
+		u, ok := r.s.users[userID]
+		if !ok {
+			return errs.ErrUserNotFound
+		}
+		u.Roles = slices.Clone(roles)
+		return nil
+	})
+}
+
+// SetBlocked blocks the user when blockedAt is set and unblocks otherwise.
+// Blocking revokes all sessions of the user.
+func (r *AdminRepository) SetBlocked(
+	ctx context.Context, userID string, blockedAt *time.Time, entry *models.AuditEntry) error {
+
+	return r.withAudit(entry, func() error {
+		u, ok := r.s.users[userID]
+		if !ok {
+			return errs.ErrUserNotFound
+		}
+		u.BlockedAt = blockedAt
+		if blockedAt != nil {
+			r.s.revokeSessions(userID, *blockedAt)
+		}
+		return nil
+	})
+}
+
+// UnlockLogin forgets the failed login attempts of the login.
+func (r *AdminRepository) UnlockLogin(ctx context.Context, login string, entry *models.AuditEntry) error {
+	return r.withAudit(entry, func() error {
+		delete(r.s.loginAttempts, loginAttemptKey{scope: models.LoginAttemptScopeLogin, key: login})
+		return nil
+	})
+}
+
+// RequeueOrder makes an order due for the next accrual poll, resets its
+// back-off and clears the manual review flag.
+func (r *AdminRepository) RequeueOrder(ctx context.Context, numberOrder string, entry *models.AuditEntry) error {
+	return r.withAudit(entry, func() error {
+		o, ok := r.s.orders[numberOrder]
+		if !ok {
+			return errs.ErrOrderNotFound
+		}
+		if o.Status.IsFinal() {
+			return errs.ErrOrderAlreadyFinal
+		}
+		o.Attempts = 0
+		o.Rejections = 0
+		o.NextAttemptAt = time.Now().UTC()
+		o.LockedUntil = nil
+		o.NeedsReview = false
+		return nil
+	})
+}
+
+// AdjustBalance credits or debits the user balance. A debit larger than the
+// current balance fails with errs.ErrBalanceInsufficient.
+func (r *AdminRepository) AdjustBalance(
+	ctx context.Context, adj *models.BalanceAdjustment, entry *models.AuditEntry) error {
+
+	return r.withAudit(entry, func() error {
+		balance, ok := r.s.balances[adj.UserID]
+		if !ok {
+			return errs.ErrUserNotFound
+		}
+		if balance.Current+adj.Amount < 0 {
+			return errs.ErrBalanceInsufficient
+		}
+
+		ledger := models.NewLedgerEntry(adj.UserID, models.LedgerEntryAdjustment, "", adj.Amount)
+		err := r.s.applyLedgerEntry(ledger, 0)
+		if err != nil {
+			return err
+		}
+		c := *adj
+		r.s.adjustments = append(r.s.adjustments, &c)
+		return nil
+	})
+}
+
+func (r *AdminRepository) GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var adjustments []*models.BalanceAdjustment
+	for _, a := range slices.Backward(r.s.adjustments) {
+		if a.UserID == userID {
+			c := *a
+			adjustments = append(adjustments, &c)
+		}
+	}
+	sortByTimeDesc(adjustments, func(a *models.BalanceAdjustment) time.Time { return a.CreatedAt })
+	return adjustments, nil
+}
+
+func (r *AdminRepository) GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	entries := make([]*models.AuditEntry, 0, len(r.s.auditLog))
+	for _, e := range slices.Backward(r.s.auditLog) {
+		c := *e
+		entries = append(entries, &c)
+	}
+	sortByTimeDesc(entries, func(e *models.AuditEntry) time.Time { return e.CreatedAt })
+	return page(entries, limit, offset), nil
+}
+
+// withAudit runs action and records entry only if it succeeded, both under the storage lock.
+func (r *AdminRepository) withAudit(entry *models.AuditEntry, action func() error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	err := action()
+	if err != nil {
+		return err
+	}
+	c := *entry
+	r.s.auditLog = append(r.s.auditLog, &c)
+	return nil
+}
+
+func page[T any](records []T, limit, offset int) []T {
+	if offset >= len(records) {
+		return nil
+	}
+	return records[offset:min(offset+limit, len(records))]
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type BalanceRepository struct {
+	s *Storage
+}
+
+func NewBalanceRepository(s *Storage) *BalanceRepository {
+	return &BalanceRepository{s: s}
+}
+
+func (r *BalanceRepository) GetUserBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	balance, ok := r.s.balances[userID]
+	if !ok {
+		return nil, errs.ErrUserNotFound
+	}
+	c := *balance
+	return &c, nil
+}
+
+func (r *BalanceRepository) GetBalanceMismatches(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	accrued := make(map[string]models.Points)
+	for _, o := range r.s.orders {
+		accrued[o.UserID] += accrualValue(o.Accrual)
+	}
+	withdrawn := make(map[string]models.Points)
+	for _, w := range r.s.withdrawals {
+		withdrawn[w.UserID] += w.Sum
+	}
+	adjusted := make(map[string]models.Points)
+	for _, a := range r.s.adjustments {
+		adjusted[a.UserID] += a.Amount
+	}
+
+	var mismatches []*models.BalanceMismatch
+	for userID, b := range r.s.balances {
+		expectedCurrent := accrued[userID] + adjusted[userID] - withdrawn[userID]
+		if b.Current != expectedCurrent || b.Withdrawn != withdrawn[userID] {
+			mismatches = append(mismatches, &models.BalanceMismatch{
+				UserID:            userID,
+				Current:           b.Current,
+				Withdrawn:         b.Withdrawn,
+				ExpectedCurrent:   expectedCurrent,
+				ExpectedWithdrawn: withdrawn[userID],
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+func (r *BalanceRepository) GetUserLedger(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var entries []*models.LedgerEntry
+	for _, e := range slices.Backward(r.s.ledger) {
+		if e.UserID == userID {
+			c := *e
+			entries = append(entries, &c)
+		}
+	}
+	sortByTimeDesc(entries, func(e *models.LedgerEntry) time.Time { return e.CreatedAt })
+	return entries, nil
+}
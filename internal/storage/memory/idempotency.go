@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type IdempotencyRepository struct {
+	s *Storage
+}
+
+func NewIdempotencyRepository(s *Storage) *IdempotencyRepository {
+	return &IdempotencyRepository{s: s}
+}
+
+// Create stores the key unless it already exists and reports whether it was stored.
+func (r *IdempotencyRepository) Create(ctx context.Context, rec *models.IdempotencyRecord) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	k := idempotencyKey{userID: rec.UserID, key: rec.Key}
+	if _, ok := r.s.idempotency[k]; ok {
+		return false, nil
+	}
+	c := *rec
+	r.s.idempotency[k] = &c
+	return true, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.idempotency[idempotencyKey{userID: userID, key: key}]
+	if !ok {
+		return nil, errs.ErrIdempotencyKeyNotFound
+	}
+	c := *rec
+	c.Body = slices.Clone(rec.Body)
+	return &c, nil
+}
+
+// Reclaim extends the lease of a key left without a response after its lease
+// expired and reports whether it was taken over.
+func (r *IdempotencyRepository) Reclaim(ctx context.Context, userID, key string, lockedUntil time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.idempotency[idempotencyKey{userID: userID, key: key}]
+	if !ok || rec.Completed() || rec.Locked(time.Now().UTC()) {
+		return false, nil
+	}
+	lockedUntil = lockedUntil.UTC()
+	rec.LockedUntil = &lockedUntil
+	return true, nil
+}
+
+func (r *IdempotencyRepository) SaveResponse(ctx context.Context, rec *models.IdempotencyRecord) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.idempotency[idempotencyKey{userID: rec.UserID, key: rec.Key}]
+	if !ok || !sameLease(stored, *rec.LockedUntil) {
+		return errs.ErrIdempotencyLeaseLost
+	}
+	stored.StatusCode = rec.StatusCode
+	stored.ContentType = rec.ContentType
+	stored.Body = slices.Clone(rec.Body)
+	return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, userID, key string, lockedUntil time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	k := idempotencyKey{userID: userID, key: key}
+	if rec, ok := r.s.idempotency[k]; ok && sameLease(rec, lockedUntil) {
+		delete(r.s.idempotency, k)
+	}
+	return nil
+}
+
+func sameLease(rec *models.IdempotencyRecord, lockedUntil time.Time) bool {
+	return rec.LockedUntil != nil && rec.LockedUntil.Equal(lockedUntil)
+}
+
+func (r *IdempotencyRepository) DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var deleted int64
+	for k, rec := range r.s.idempotency {
+		if rec.CreatedAt.Before(before) {
+			delete(r.s.idempotency, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
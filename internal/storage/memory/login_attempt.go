@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type LoginAttemptRepository struct {
+	s *Storage
+}
+
+func NewLoginAttemptRepository(s *Storage) *LoginAttemptRepository {
+	return &LoginAttemptRepository{s: s}
+}
+
+func (r *LoginAttemptRepository) Get(
+	ctx context.Context, scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	attempt, ok := r.s.loginAttempts[loginAttemptKey{scope: scope, key: key}]
+	if !ok {
+		return nil, errs.ErrLoginAttemptsNotFound
+	}
+	c := *attempt
+	return &c, nil
+}
+
+// RegisterFailure increments the failure counter and returns the updated
+// record. Failures older than resetBefore are forgotten first.
+func (r *LoginAttemptRepository) RegisterFailure(
+	ctx context.Context, scope models.LoginAttemptScope, key string,
+	now, resetBefore time.Time) (*models.LoginAttempt, error) {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	k := loginAttemptKey{scope: scope, key: key}
+	attempt, ok := r.s.loginAttempts[k]
+	switch {
+	case !ok:
+		attempt = &models.LoginAttempt{Scope: scope, Key: key, Failures: 1}
+		r.s.loginAttempts[k] = attempt
+	case attempt.LastFailureAt.Before(resetBefore):
+		attempt.Failures = 1
+	default:
+		attempt.Failures++
+	}
+	attempt.LastFailureAt = now
+
+	c := *attempt
+	return &c, nil
+}
+
+func (r *LoginAttemptRepository) Lock(
+	ctx context.Context, scope models.LoginAttemptScope, key string, until time.Time) error {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if attempt, ok := r.s.loginAttempts[loginAttemptKey{scope: scope, key: key}]; ok {
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) Reset(ctx context.Context, scope models.LoginAttemptScope, key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.loginAttempts, loginAttemptKey{scope: scope, key: key})
+	return nil
+}
+
+// DeleteStale removes counters without recent failures and without an active lock.
+func (r *LoginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for k, attempt := range r.s.loginAttempts {
+		if attempt.LastFailureAt.Before(before) && !attempt.Locked(now) {
+			delete(r.s.loginAttempts, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type OrderRepository struct {
+	s *Storage
+}
+
+func NewOrderRepository(s *Storage) *OrderRepository {
+	return &OrderRepository{s: s}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if existing, ok := r.s.orders[order.Number]; ok {
+		if existing.UserID != order.UserID {
+			return errs.ErrOrderAlreadyUploadedByOtherUser
+		}
+		return errs.ErrOrderAlreadyUploadedByThisUser
+	}
+	if _, ok := r.s.users[order.UserID]; !ok {
+		return errs.ErrUserNotFound
+	}
+	r.s.orders[order.Number] = copyOrder(order)
+	return nil
+}
+
+func (r *OrderRepository) GetByNumber(ctx context.Context, number string) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	order, ok := r.s.orders[number]
+	if !ok {
+		return nil, errs.ErrOrderNotFound
+	}
+	return copyOrder(order), nil
+}
+
+func (r *OrderRepository) GetUserOrders(ctx context.Context, userID string) ([]*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var orders []*models.Order
+	for _, o := range r.s.orders {
+		if o.UserID == userID {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	slices.SortFunc(orders, func(a, b *models.Order) int { return strings.Compare(a.Number, b.Number) })
+	sortByTimeDesc(orders, func(o *models.Order) time.Time { return o.UploadedAt })
+	return orders, nil
+}
+
+func (r *OrderRepository) CountOrdersByStatus(ctx context.Context) (map[models.OrderStatus]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	counts := make(map[models.OrderStatus]int)
+	for _, o := range r.s.orders {
+		counts[o.Status]++
+	}
+	return counts, nil
+}
+
+// ClaimOrdersToAccrualUpdate leases up to limit due orders, earliest
+// next attempt first.
+func (r *OrderRepository) ClaimOrdersToAccrualUpdate(
+	ctx context.Context, limit int, lease time.Duration) ([]*models.Order, error) {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []*models.Order
+	for _, o := range r.s.orders {
+		if o.Status.IsFinal() || o.NeedsReview || o.NextAttemptAt.After(now) {
+			continue
+		}
+		if o.LockedUntil != nil && o.LockedUntil.After(now) {
+			continue
+		}
+		due = append(due, o)
+	}
+	slices.SortFunc(due, func(a, b *models.Order) int { return a.NextAttemptAt.Compare(b.NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.Order, 0, len(due))
+	lockedUntil := now.Add(lease)
+	for _, o := range due {
+		o.LockedUntil = &lockedUntil
+		claimed = append(claimed, copyOrder(o))
+	}
+	return claimed, nil
+}
+
+// ScheduleAccrualRetry releases a claimed order after a failed poll and
+// postpones its next attempt by delay.
+func (r *OrderRepository) ScheduleAccrualRetry(ctx context.Context, numberOrder string, delay time.Duration) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.orders[numberOrder]
+	if !ok {
+		return errs.ErrOrderNotFound
+	}
+	o.Attempts++
+	o.Rejections = 0
+	o.NextAttemptAt = time.Now().UTC().Add(delay)
+	o.LockedUntil = nil
+	return nil
+}
+
+// ScheduleAccrualRejection is ScheduleAccrualRetry for a poll whose response
+// was rejected; it also counts the rejection in a row.
+func (r *OrderRepository) ScheduleAccrualRejection(ctx context.Context, numberOrder string, delay time.Duration) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.orders[numberOrder]
+	if !ok {
+		return errs.ErrOrderNotFound
+	}
+	o.Attempts++
+	o.Rejections++
+	o.NextAttemptAt = time.Now().UTC().Add(delay)
+	o.LockedUntil = nil
+	return nil
+}
+
+// HoldAccrualForReview releases a claimed order and excludes it from polling
+// until an administrator requeues it.
+func (r *OrderRepository) HoldAccrualForReview(ctx context.Context, numberOrder string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.orders[numberOrder]
+	if !ok {
+		return errs.ErrOrderNotFound
+	}
+	o.Attempts++
+	o.Rejections++
+	o.NeedsReview = true
+	o.LockedUntil = nil
+	return nil
+}
+
+// UpdateStatusAndAccural updates the order and credits the accrual
+// difference to the user balance and ledger.
+func (r *OrderRepository) UpdateStatusAndAccural(
+	ctx context.Context,
+	numberOrder string,
+	status models.OrderStatus,
+	accrual *models.Points) error {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.orders[numberOrder]
+	if !ok {
+		return errs.ErrOrderNotFound
+	}
+	if !slices.Contains(models.PreviousStatuses(status), o.Status) {
+		return fmt.Errorf("%w: %s -> %s", errs.ErrOrderStatusTransition, o.Status, status)
+	}
+
+	delta := accrualValue(accrual) - accrualValue(o.Accrual)
+	if delta != 0 {
+		entry := models.NewLedgerEntry(o.UserID, models.LedgerEntryAccrual, o.Number, delta)
+		err := r.s.applyLedgerEntry(entry, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	if accrual != nil {
+		v := *accrual
+		accrual = &v
+	}
+	o.Status = status
+	o.Accrual = accrual
+	o.Attempts = 0
+	o.Rejections = 0
+	o.NextAttemptAt = time.Now().UTC()
+	o.LockedUntil = nil
+	return nil
+}
+
+func accrualValue(accrual *models.Points) models.Points {
+	if accrual == nil {
+		return 0
+	}
+	return *accrual
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type SessionRepository struct {
+	s *Storage
+}
+
+func NewSessionRepository(s *Storage) *SessionRepository {
+	return &SessionRepository{s: s}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, s *models.Session) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c := *s
+	r.s.sessions[s.ID] = &c
+	return nil
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s, ok := r.s.sessions[id]
+	if !ok {
+		return nil, errs.ErrSessionNotFound
+	}
+	c := *s
+	return &c, nil
+}
+
+func (r *SessionRepository) GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, s := range r.s.sessions {
+		if s.RefreshTokenHash == hash {
+			c := *s
+			return &c, nil
+		}
+	}
+	return nil, errs.ErrSessionNotFound
+}
+
+// Rotate replaces the refresh token of an active session. It fails with
+// errs.ErrSessionNotFound if the old token was already rotated or revoked.
+func (r *SessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s, ok := r.s.sessions[id]
+	if !ok || s.RefreshTokenHash != oldHash || s.RevokedAt != nil {
+		return errs.ErrSessionNotFound
+	}
+	s.RefreshTokenHash = newHash
+	s.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if s, ok := r.s.sessions[id]; ok && s.RevokedAt == nil {
+		s.RevokedAt = &at
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.revokeSessions(userID, at)
+	return nil
+}
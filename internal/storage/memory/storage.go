@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+// Storage keeps all data in process memory. One mutex guards everything, so
+// operations touching several entities are atomic like the postgres
+// transactions. Records are copied on the way in and out, callers never share
+// pointers with the storage.
+type Storage struct {
+	mu sync.Mutex
+
+	users         map[string]*models.User
+	orders        map[string]*models.Order
+	withdrawals   []*models.Withdrawal
+	balances      map[string]*models.Balance
+	ledger        []*models.LedgerEntry
+	idempotency   map[idempotencyKey]*models.IdempotencyRecord
+	sessions      map[string]*models.Session
+	loginAttempts map[loginAttemptKey]*models.LoginAttempt
+	auditLog      []*models.AuditEntry
+	adjustments   []*models.BalanceAdjustment
+	recoveryCodes map[string]map[string]*time.Time
+}
+
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+type loginAttemptKey struct {
+	scope models.LoginAttemptScope
+	key   string
+}
+
+func New() *Storage {
+	return &Storage{
+		users:         make(map[string]*models.User),
+		orders:        make(map[string]*models.Order),
+		balances:      make(map[string]*models.Balance),
+		idempotency:   make(map[idempotencyKey]*models.IdempotencyRecord),
+		sessions:      make(map[string]*models.Session),
+		loginAttempts: make(map[loginAttemptKey]*models.LoginAttempt),
+		recoveryCodes: make(map[string]map[string]*time.Time),
+	}
+}
+
+// applyLedgerEntry is the counterpart of the postgres helper, the caller must hold s.mu.
+func (s *Storage) applyLedgerEntry(entry *models.LedgerEntry, withdrawn models.Points) error {
+	balance, ok := s.balances[entry.UserID]
+	if !ok {
+		return errs.ErrUserNotFound
+	}
+	balance.Current += entry.Amount
+	balance.Withdrawn += withdrawn
+	entry.BalanceAfter = balance.Current
+
+	e := *entry
+	s.ledger = append(s.ledger, &e)
+	return nil
+}
+
+func (s *Storage) userByLogin(login string) *models.User {
+	for _, u := range s.users {
+		if u.Login == login {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Storage) revokeSessions(userID string, at time.Time) {
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			revokedAt := at
+			session.RevokedAt = &revokedAt
+		}
+	}
+}
+
+func copyUser(u *models.User) *models.User {
+	c := *u
+	c.Roles = slices.Clone(u.Roles)
+	return &c
+}
+
+func copyOrder(o *models.Order) *models.Order {
+	c := *o
+	return &c
+}
+
+// sortByTimeDesc orders records newest first like ORDER BY ... DESC.
+func sortByTimeDesc[T any](records []T, at func(T) time.Time) {
+	slices.SortStableFunc(records, func(a, b T) int {
+		return at(b).Compare(at(a))
+	})
+}
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/storagetest"
+)
+
+func TestContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *services.Repositories {
+		s := New()
+		return &services.Repositories{
+			Users:         NewUserRepository(s),
+			Orders:        NewOrderRepository(s),
+			Withdrawals:   NewWithdrawalRepository(s),
+			Balance:       NewBalanceRepository(s),
+			Idempotency:   NewIdempotencyRepository(s),
+			Sessions:      NewSessionRepository(s),
+			Admin:         NewAdminRepository(s),
+			LoginAttempts: NewLoginAttemptRepository(s),
+			TwoFactor:     NewTwoFactorRepository(s),
+		}
+	})
+}
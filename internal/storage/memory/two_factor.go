@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+)
+
+type TwoFactorRepository struct {
+	s *Storage
+}
+
+func NewTwoFactorRepository(s *Storage) *TwoFactorRepository {
+	return &TwoFactorRepository{s: s}
+}
+
+// SetPendingSecret stores a new secret that is not used for login until it
+// is confirmed. It fails if two-factor authentication is already enabled.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[userID]
+	if !ok || u.TOTPEnabledAt != nil {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+	u.TOTPSecret = &secret
+	u.TOTPLastStep = nil
+	return nil
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes.
+func (r *TwoFactorRepository) Enable(
+	ctx context.Context, userID string, at time.Time, step int64, recoveryCodeHashes []string) error {
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[userID]
+	if !ok || u.TOTPSecret == nil || u.TOTPEnabledAt != nil {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+	u.TOTPEnabledAt = &at
+	u.TOTPLastStep = &step
+
+	codes := make(map[string]*time.Time, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = nil
+	}
+	r.s.recoveryCodes[userID] = codes
+	return nil
+}
+
+// UseStep records the time step of an accepted code. It reports false if the
+// step or a later one was already used, which rejects replayed codes.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[userID]
+	if !ok || (u.TOTPLastStep != nil && *u.TOTPLastStep >= step) {
+		return false, nil
+	}
+	u.TOTPLastStep = &step
+	return true, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether it existed.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	usedAt, ok := r.s.recoveryCodes[userID][codeHash]
+	if !ok || usedAt != nil {
+		return false, nil
+	}
+	r.s.recoveryCodes[userID][codeHash] = &at
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type UserRepository struct {
+	s *Storage
+}
+
+func NewUserRepository(s *Storage) *UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (r *UserRepository) Create(ctx context.Context, u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.userByLogin(u.Login) != nil {
+		return errs.ErrUserAlreadyExists
+	}
+	r.s.users[u.ID] = copyUser(u)
+	r.s.balances[u.ID] = &models.Balance{UserID: u.ID}
+	return nil
+}
+
+func (r *UserRepository) GetByLogin(ctx context.Context, login string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u := r.s.userByLogin(login)
+	if u == nil {
+		return nil, errs.ErrUserNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) UserExists(ctx context.Context, login string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.userByLogin(login) != nil, nil
+}
+
+func (r *UserRepository) UpdateLoginTime(ctx context.Context, userID string, time time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if u, ok := r.s.users[userID]; ok {
+		u.LastLoginAt = &time
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if u, ok := r.s.users[userID]; ok {
+		u.PasswordHash = passwordHash
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password hash and revokes all sessions of the user.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[userID]
+	if !ok {
+		return errs.ErrUserNotFound
+	}
+	u.PasswordHash = passwordHash
+	r.s.revokeSessions(userID, at)
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, errs.ErrUserNotFound
+	}
+	return copyUser(u), nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+)
+
+type WithdrawalRepository struct {
+	s *Storage
+}
+
+func NewWithdrawalRepository(s *Storage) *WithdrawalRepository {
+	return &WithdrawalRepository{s: s}
+}
+
+func (r *WithdrawalRepository) Withdraw(ctx context.Context, w *models.Withdrawal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	balance, ok := r.s.balances[w.UserID]
+	if !ok {
+		return errs.ErrUserNotFound
+	}
+	for _, existing := range r.s.withdrawals {
+		if existing.UserID == w.UserID && existing.OrderNumber == w.OrderNumber {
+			return errs.ErrWithdrawalAlreadyProcessed
+		}
+	}
+	if balance.Current < w.Sum {
+		return errs.ErrBalanceInsufficient
+	}
+
+	entry := models.NewLedgerEntry(w.UserID, models.LedgerEntryWithdrawal, w.OrderNumber, -w.Sum)
+	err := r.s.applyLedgerEntry(entry, w.Sum)
+	if err != nil {
+		return err
+	}
+	c := *w
+	r.s.withdrawals = append(r.s.withdrawals, &c)
+	return nil
+}
+
+func (r *WithdrawalRepository) GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var withdrawals []*models.Withdrawal
+	for _, w := range slices.Backward(r.s.withdrawals) {
+		if w.UserID == userID {
+			c := *w
+			withdrawals = append(withdrawals, &c)
+		}
+	}
+	sortByTimeDesc(withdrawals, func(w *models.Withdrawal) time.Time { return w.ProcessedAt })
+	return withdrawals, nil
+}
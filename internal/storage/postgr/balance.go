@@ -21,17 +21,49 @@ func NewBalanceRepository(db *sqlx.DB) *BalanceRepository {
 }
 
 func (r *BalanceRepository) GetUserBalance(ctx context.Context, userID string) (*models.Balance, error) {
-	balance := models.Balance{}
-	balance.UserID = userID
+	return getUserBalance(ctx, r.db, userID, false)
+}
+
+func (r *BalanceRepository) GetBalanceMismatches(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	var mismatches []*models.BalanceMismatch
 	query := `
-		SELECT 
-			o.total_accrual - w.total_withdrawn as current,
-			w.total_withdrawn as withdrawn
-		FROM 
-			(SELECT COALESCE(SUM(accrual), 0) as total_accrual FROM orders WHERE user_id = $1) o,
-			(SELECT COALESCE(SUM(sum), 0) as total_withdrawn FROM withdrawals WHERE user_id = $1) w;
+		SELECT
+			b.user_id,
+			b.current,
+			b.withdrawn,
+			COALESCE(o.total_accrual, 0) + COALESCE(a.total_adjusted, 0) - COALESCE(w.total_withdrawn, 0) AS expected_current,
+			COALESCE(w.total_withdrawn, 0) AS expected_withdrawn
+		FROM balances b
+		LEFT JOIN (SELECT user_id, SUM(accrual) AS total_accrual FROM orders GROUP BY user_id) o ON o.user_id = b.user_id
+		LEFT JOIN (SELECT user_id, SUM(sum) AS total_withdrawn FROM withdrawals GROUP BY user_id) w ON w.user_id = b.user_id
+		LEFT JOIN (SELECT user_id, SUM(amount) AS total_adjusted FROM balance_adjustments GROUP BY user_id) a ON a.user_id = b.user_id
+		WHERE b.current <> COALESCE(o.total_accrual, 0) + COALESCE(a.total_adjusted, 0) - COALESCE(w.total_withdrawn, 0)
+		   OR b.withdrawn <> COALESCE(w.total_withdrawn, 0)
 	`
-	err := r.db.GetContext(ctx, &balance, query, userID)
+	err := r.db.SelectContext(ctx, &mismatches, query)
+	if err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
+
+func (r *BalanceRepository) GetUserLedger(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	var entries []*models.LedgerEntry
+	query := `SELECT * FROM ledger_entries WHERE user_id = $1 ORDER BY created_at DESC`
+	err := r.db.SelectContext(ctx, &entries, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func getUserBalance(ctx context.Context, q sqlx.QueryerContext, userID string, forUpdate bool) (*models.Balance, error) {
+	balance := models.Balance{}
+	query := `SELECT user_id, current, withdrawn FROM balances WHERE user_id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	err := sqlx.GetContext(ctx, q, &balance, query, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errs.ErrUserNotFound
@@ -40,3 +72,30 @@ func (r *BalanceRepository) GetUserBalance(ctx context.Context, userID string) (
 	}
 	return &balance, nil
 }
+
+func createBalance(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO balances (user_id, current, withdrawn) VALUES ($1, 0, 0)`, userID)
+	return err
+}
+
+// applyLedgerEntry moves the user balance by entry.Amount, records the entry
+// with the resulting running balance and adds withdrawn to the withdrawn total.
+func applyLedgerEntry(ctx context.Context, tx *sqlx.Tx, entry *models.LedgerEntry, withdrawn models.Points) error {
+	query := `UPDATE balances
+			  SET current = current + $1, withdrawn = withdrawn + $2
+			  WHERE user_id = $3
+			  RETURNING current`
+	err := tx.GetContext(ctx, &entry.BalanceAfter, query, entry.Amount, withdrawn, entry.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrUserNotFound
+		}
+		return err
+	}
+
+	query = `INSERT INTO ledger_entries (id, user_id, kind, order_number, amount, balance_after, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.ExecContext(ctx, query,
+		entry.ID, entry.UserID, entry.Kind, entry.OrderNumber, entry.Amount, entry.BalanceAfter, entry.CreatedAt)
+	return err
+}
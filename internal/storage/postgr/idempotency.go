@@ -0,0 +1,102 @@
+package postgr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create stores the key unless it already exists and reports whether it was stored.
+func (r *IdempotencyRepository) Create(ctx context.Context, rec *models.IdempotencyRecord) (bool, error) {
+	query := `INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, locked_until)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (user_id, key) DO NOTHING`
+	res, err := r.db.ExecContext(ctx, query, rec.UserID, rec.Key, rec.RequestHash, rec.CreatedAt, rec.LockedUntil)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	rec := &models.IdempotencyRecord{}
+	query := `SELECT * FROM idempotency_keys WHERE user_id = $1 AND key = $2`
+	err := r.db.GetContext(ctx, rec, query, userID, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Reclaim extends the lease of a key left without a response after its lease
+// expired and reports whether it was taken over.
+func (r *IdempotencyRepository) Reclaim(ctx context.Context, userID, key string, lockedUntil time.Time) (bool, error) {
+	query := `UPDATE idempotency_keys
+			  SET locked_until = $1
+			  WHERE user_id = $2 AND key = $3
+			    AND status_code = 0
+			    AND (locked_until IS NULL OR locked_until <= now())`
+	res, err := r.db.ExecContext(ctx, query, lockedUntil, userID, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *IdempotencyRepository) SaveResponse(ctx context.Context, rec *models.IdempotencyRecord) error {
+	query := `UPDATE idempotency_keys
+			  SET status_code = $1, content_type = $2, body = $3
+			  WHERE user_id = $4 AND key = $5 AND locked_until = $6`
+	res, err := r.db.ExecContext(ctx, query,
+		rec.StatusCode, rec.ContentType, rec.Body, rec.UserID, rec.Key, rec.LockedUntil)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, userID, key string, lockedUntil time.Time) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND locked_until = $3`
+	_, err := r.db.ExecContext(ctx, query, userID, key, lockedUntil)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
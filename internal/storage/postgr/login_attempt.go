@@ -0,0 +1,81 @@
+package postgr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginAttemptRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Get(
+	ctx context.Context, scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+
+	attempt := &models.LoginAttempt{}
+	query := `SELECT * FROM login_attempts WHERE scope = $1 AND key = $2`
+	err := r.db.GetContext(ctx, attempt, query, scope, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrLoginAttemptsNotFound
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// RegisterFailure increments the failure counter and returns the updated
+// record. Failures older than resetBefore are forgotten first.
+func (r *LoginAttemptRepository) RegisterFailure(
+	ctx context.Context, scope models.LoginAttemptScope, key string,
+	now, resetBefore time.Time) (*models.LoginAttempt, error) {
+
+	attempt := &models.LoginAttempt{}
+	query := `INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+			  VALUES ($1, $2, 1, $3)
+			  ON CONFLICT (scope, key) DO UPDATE
+			  SET failures = CASE WHEN login_attempts.last_failure_at < $4 THEN 1
+			                      ELSE login_attempts.failures + 1 END,
+			      last_failure_at = EXCLUDED.last_failure_at
+			  RETURNING *`
+	err := r.db.GetContext(ctx, attempt, query, scope, key, now, resetBefore)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (r *LoginAttemptRepository) Lock(
+	ctx context.Context, scope models.LoginAttemptScope, key string, until time.Time) error {
+
+	query := `UPDATE login_attempts SET locked_until = $1 WHERE scope = $2 AND key = $3`
+	_, err := r.db.ExecContext(ctx, query, until, scope, key)
+	return err
+}
+
+func (r *LoginAttemptRepository) Reset(ctx context.Context, scope models.LoginAttemptScope, key string) error {
+	query := `DELETE FROM login_attempts WHERE scope = $1 AND key = $2`
+	_, err := r.db.ExecContext(ctx, query, scope, key)
+	return err
+}
+
+// DeleteStale removes counters without recent failures and without an active lock.
+func (r *LoginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM login_attempts
+			  WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < now())`
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
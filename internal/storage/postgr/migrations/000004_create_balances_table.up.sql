@@ -0,0 +1,14 @@
+CREATE TABLE balances (
+    user_id UUID PRIMARY KEY REFERENCES users(id),
+    current DECIMAL(10,2) NOT NULL DEFAULT 0,
+    withdrawn DECIMAL(10,2) NOT NULL DEFAULT 0
+);
+
+INSERT INTO balances (user_id, current, withdrawn)
+SELECT
+    u.id,
+    COALESCE(o.total_accrual, 0) - COALESCE(w.total_withdrawn, 0),
+    COALESCE(w.total_withdrawn, 0)
+FROM users u
+LEFT JOIN (SELECT user_id, SUM(accrual) AS total_accrual FROM orders GROUP BY user_id) o ON o.user_id = u.id
+LEFT JOIN (SELECT user_id, SUM(sum) AS total_withdrawn FROM withdrawals GROUP BY user_id) w ON w.user_id = u.id;
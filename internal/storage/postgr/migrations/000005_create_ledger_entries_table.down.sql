@@ -0,0 +1 @@
+DROP TABLE ledger_entries;
\ No newline at end of file
@@ -0,0 +1,29 @@
+CREATE TABLE ledger_entries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    kind VARCHAR(50) NOT NULL,
+    order_number VARCHAR(255) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    balance_after DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX ledger_entries_user_id_created_at_idx ON ledger_entries (user_id, created_at);
+
+INSERT INTO ledger_entries (id, user_id, kind, order_number, amount, balance_after, created_at)
+SELECT
+    gen_random_uuid(),
+    e.user_id,
+    e.kind,
+    e.order_number,
+    e.amount,
+    SUM(e.amount) OVER (PARTITION BY e.user_id ORDER BY e.created_at, e.kind ROWS UNBOUNDED PRECEDING),
+    e.created_at
+FROM (
+    SELECT user_id, 'accrual' AS kind, number AS order_number, accrual AS amount, uploaded_at AS created_at
+    FROM orders
+    WHERE accrual IS NOT NULL AND accrual <> 0
+    UNION ALL
+    SELECT user_id, 'withdrawal' AS kind, order_number, -sum AS amount, processed_at AS created_at
+    FROM withdrawals
+) e;
@@ -0,0 +1,6 @@
+DROP INDEX orders_accrual_queue_idx;
+
+ALTER TABLE orders
+    DROP COLUMN attempts,
+    DROP COLUMN next_attempt_at,
+    DROP COLUMN locked_until;
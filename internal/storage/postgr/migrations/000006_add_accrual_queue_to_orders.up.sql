@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN locked_until TIMESTAMPTZ;
+
+CREATE INDEX orders_accrual_queue_idx ON orders (next_attempt_at) WHERE status NOT IN ('INVALID', 'PROCESSED');
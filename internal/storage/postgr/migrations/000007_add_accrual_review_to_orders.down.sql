@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN rejections,
+    DROP COLUMN needs_review;
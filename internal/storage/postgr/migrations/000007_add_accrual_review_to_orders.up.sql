@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    ADD COLUMN needs_review BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN rejections INT NOT NULL DEFAULT 0;
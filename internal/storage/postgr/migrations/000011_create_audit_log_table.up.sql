@@ -0,0 +1,12 @@
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL REFERENCES users(id),
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(255) NOT NULL,
+    details JSONB,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX audit_log_target_idx ON audit_log (target_type, target_id);
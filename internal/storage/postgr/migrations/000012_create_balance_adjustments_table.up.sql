@@ -0,0 +1,11 @@
+CREATE TABLE balance_adjustments (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    amount DECIMAL(10,2) NOT NULL,
+    reason TEXT NOT NULL,
+    ticket VARCHAR(255) NOT NULL,
+    operator_id UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX balance_adjustments_user_id_idx ON balance_adjustments (user_id);
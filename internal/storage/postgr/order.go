@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/models"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type OrderRepository struct {
@@ -19,9 +22,20 @@ func NewOrderRepository(db *sqlx.DB) *OrderRepository {
 }
 
 func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
-	query := `INSERT INTO orders (number, user_id, status, accrual, uploaded_at)
-			  VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecContext(ctx, query, order.Number, order.UserID, order.Status, order.Accrual, order.UploadedAt)
+	query := `INSERT INTO orders (number, user_id, status, accrual, uploaded_at, next_attempt_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query,
+		order.Number, order.UserID, order.Status, order.Accrual, order.UploadedAt, order.NextAttemptAt)
+	if isUniqueViolation(err) {
+		existing, getErr := r.GetByNumber(ctx, order.Number)
+		if getErr != nil {
+			return getErr
+		}
+		if existing.UserID != order.UserID {
+			return errs.ErrOrderAlreadyUploadedByOtherUser
+		}
+		return errs.ErrOrderAlreadyUploadedByThisUser
+	}
 	return err
 }
 
@@ -51,30 +65,169 @@ func (r *OrderRepository) GetUserOrders(ctx context.Context, userID string) ([]*
 	return orders, nil
 }
 
-func (r *OrderRepository) GetOrdersToAccrualUpdate(ctx context.Context) ([]*models.Order, error) {
+func (r *OrderRepository) CountOrdersByStatus(ctx context.Context) (map[models.OrderStatus]int, error) {
+	var rows []struct {
+		Status models.OrderStatus `db:"status"`
+		Count  int                `db:"count"`
+	}
+	query := `SELECT status, count(*) AS count FROM orders GROUP BY status`
+	err := r.db.SelectContext(ctx, &rows, query)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[models.OrderStatus]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ClaimOrdersToAccrualUpdate locks up to limit due orders for the lease time.
+// Rows already claimed by another instance are skipped, so several replicas
+// can poll the accrual system without processing the same order twice.
+func (r *OrderRepository) ClaimOrdersToAccrualUpdate(
+	ctx context.Context, limit int, lease time.Duration) ([]*models.Order, error) {
+
 	var orders []*models.Order
-	query := `SELECT * 
-			  FROM orders 
-			  WHERE status not in ($1, $2)`
-	err := r.db.SelectContext(ctx, &orders, query, models.StatusInvalid, models.StatusProcessed)
+	query := `UPDATE orders
+			  SET locked_until = now() + make_interval(secs => $1)
+			  WHERE number IN (
+				  SELECT number
+				  FROM orders
+				  WHERE status NOT IN ($2, $3)
+				    AND NOT needs_review
+				    AND next_attempt_at <= now()
+				    AND (locked_until IS NULL OR locked_until <= now())
+				  ORDER BY next_attempt_at
+				  LIMIT $4
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING *`
+	err := r.db.SelectContext(ctx, &orders, query,
+		lease.Seconds(), models.StatusInvalid, models.StatusProcessed, limit)
 	if err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
 
+// ScheduleAccrualRetry releases a claimed order after a failed poll and
+// postpones its next attempt by delay.
+func (r *OrderRepository) ScheduleAccrualRetry(ctx context.Context, numberOrder string, delay time.Duration) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1,
+			      rejections = 0,
+			      next_attempt_at = now() + make_interval(secs => $1),
+			      locked_until = NULL
+			  WHERE number = $2`
+	return r.scheduleAccrual(ctx, query, delay, numberOrder)
+}
+
+// ScheduleAccrualRejection is ScheduleAccrualRetry for a poll whose response
+// was rejected; it also counts the rejection in a row.
+func (r *OrderRepository) ScheduleAccrualRejection(ctx context.Context, numberOrder string, delay time.Duration) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1,
+			      rejections = rejections + 1,
+			      next_attempt_at = now() + make_interval(secs => $1),
+			      locked_until = NULL
+			  WHERE number = $2`
+	return r.scheduleAccrual(ctx, query, delay, numberOrder)
+}
+
+func (r *OrderRepository) scheduleAccrual(ctx context.Context, query string, delay time.Duration, numberOrder string) error {
+	res, err := r.db.ExecContext(ctx, query, delay.Seconds(), numberOrder)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+// HoldAccrualForReview releases a claimed order and excludes it from polling
+// until an administrator requeues it.
+func (r *OrderRepository) HoldAccrualForReview(ctx context.Context, numberOrder string) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1, rejections = rejections + 1, needs_review = TRUE, locked_until = NULL
+			  WHERE number = $1`
+	res, err := r.db.ExecContext(ctx, query, numberOrder)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+// UpdateStatusAndAccural updates the order and credits the accrual difference
+// to the user balance and ledger in the same transaction. The update only
+// applies if the current status may move to the new one.
 func (r *OrderRepository) UpdateStatusAndAccural(
 	ctx context.Context,
 	numberOrder string,
 	status models.OrderStatus,
-	accrual *float64) error {
+	accrual *models.Points) error {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	order := &models.Order{}
+	err = tx.GetContext(ctx, order, `SELECT * FROM orders WHERE number = $1 FOR UPDATE`, numberOrder)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrOrderNotFound
+		}
+		return err
+	}
 
+	var previous pq.StringArray
+	for _, v := range models.PreviousStatuses(status) {
+		previous = append(previous, string(v))
+	}
 	query := `UPDATE orders 
-			  SET status = $1, accrual = $2
-			  WHERE number = $3`
-	_, err := r.db.ExecContext(ctx, query, status, accrual, numberOrder)
+			  SET status = $1, accrual = $2, attempts = 0, rejections = 0, next_attempt_at = now(), locked_until = NULL
+			  WHERE number = $3 AND status = ANY($4)`
+	res, err := tx.ExecContext(ctx, query, status, accrual, numberOrder, previous)
 	if err != nil {
 		return err
 	}
-	return nil
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %s -> %s", errs.ErrOrderStatusTransition, order.Status, status)
+	}
+
+	delta := accrualValue(accrual) - accrualValue(order.Accrual)
+	if delta != 0 {
+		entry := models.NewLedgerEntry(order.UserID, models.LedgerEntryAccrual, order.Number, delta)
+		err = applyLedgerEntry(ctx, tx, entry, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func accrualValue(accrual *models.Points) models.Points {
+	if accrual == nil {
+		return 0
+	}
+	return *accrual
 }
@@ -2,19 +2,31 @@ package postgr
 
 import (
 	"context"
+	"database/sql"
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
 
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/XSAM/otelsql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
 )
 
 func NewConnection(ctx context.Context, connectionString string) (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", connectionString)
+	sqlDB, err := otelsql.Open("postgres", connectionString,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL))
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
 	err = db.PingContext(ctx)
 	if err != nil {
 		return nil, err
@@ -51,3 +63,55 @@ func runMigrations(db *sqlx.DB) error {
 
 	return nil
 }
+
+// CheckMigrations fails unless the database schema is at the latest embedded
+// migration and the last migration finished cleanly.
+func CheckMigrations(ctx context.Context, db *sqlx.DB) error {
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var state struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err = db.GetContext(ctx, &state, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: no version", errs.ErrMigrationsNotApplied)
+	}
+	if err != nil {
+		return err
+	}
+	if state.Dirty {
+		return fmt.Errorf("%w: version %d", errs.ErrMigrationsDirty, state.Version)
+	}
+	if state.Version < latest {
+		return fmt.Errorf("%w: version %d of %d", errs.ErrMigrationsNotApplied, state.Version, latest)
+	}
+	return nil
+}
+
+func latestMigrationVersion() (uint, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, e := range entries {
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad migration file name %q: %w", e.Name(), err)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+const uniqueViolationCode = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
@@ -0,0 +1,40 @@
+package postgr
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestContract needs a database that may be wiped, it is skipped unless
+// TEST_DATABASE_URI is set.
+func TestContract(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+	db, err := NewConnection(context.Background(), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	storagetest.Run(t, func(t *testing.T) *services.Repositories {
+		_, err := db.Exec(`TRUNCATE users, orders, withdrawals, balances, ledger_entries,
+			idempotency_keys, sessions, audit_log, balance_adjustments, login_attempts, recovery_codes CASCADE`)
+		require.NoError(t, err)
+		return &services.Repositories{
+			Users:         NewUserRepository(db),
+			Orders:        NewOrderRepository(db),
+			Withdrawals:   NewWithdrawalRepository(db),
+			Balance:       NewBalanceRepository(db),
+			Idempotency:   NewIdempotencyRepository(db),
+			Sessions:      NewSessionRepository(db),
+			Admin:         NewAdminRepository(db),
+			LoginAttempts: NewLoginAttemptRepository(db),
+			TwoFactor:     NewTwoFactorRepository(db),
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package postgr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, s *models.Session) error {
+	query := `INSERT INTO sessions (id, user_id, refresh_token_hash, created_at, expires_at, revoked_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query, s.ID, s.UserID, s.RefreshTokenHash, s.CreatedAt, s.ExpiresAt, s.RevokedAt)
+	return err
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	return r.get(ctx, `SELECT * FROM sessions WHERE id = $1`, id)
+}
+
+func (r *SessionRepository) GetByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	return r.get(ctx, `SELECT * FROM sessions WHERE refresh_token_hash = $1`, hash)
+}
+
+// Rotate replaces the refresh token of an active session. It fails with
+// errs.ErrSessionNotFound if the old token was already rotated or revoked.
+func (r *SessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error {
+	query := `UPDATE sessions
+			  SET refresh_token_hash = $1, expires_at = $2
+			  WHERE id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, newHash, expiresAt, id, oldHash)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, at, id)
+	return err
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, at, userID)
+	return err
+}
+
+func (r *SessionRepository) get(ctx context.Context, query string, arg string) (*models.Session, error) {
+	s := &models.Session{}
+	err := r.db.GetContext(ctx, s, query, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return s, nil
+}
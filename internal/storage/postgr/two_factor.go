@@ -0,0 +1,105 @@
+package postgr
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SetPendingSecret stores a new secret that is not used for login until it
+// is confirmed. It fails if two-factor authentication is already enabled.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_last_step = NULL
+			  WHERE id = $2 AND totp_enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes.
+func (r *TwoFactorRepository) Enable(
+	ctx context.Context, userID string, at time.Time, step int64, recoveryCodeHashes []string) error {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET totp_enabled_at = $1, totp_last_step = $2
+			  WHERE id = $3 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, at, step, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the time step of an accepted code. It reports false if the
+// step or a later one was already used, which rejects replayed codes.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1
+			  WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)`
+	res, err := r.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether it existed.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = $1
+			  WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, at, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -24,8 +24,26 @@ func (r *UserRepository) Create(ctx context.Context, u *models.User) error {
 		INSERT INTO users (id, login, password_hash, created_at, roles, last_login_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := r.db.ExecContext(ctx, query, u.ID, u.Login, u.PasswordHash, u.CreatedAt, u.Roles, u.LastLoginAt)
-	return err
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query, u.ID, u.Login, u.PasswordHash, u.CreatedAt, u.Roles, u.LastLoginAt)
+	if isUniqueViolation(err) {
+		return errs.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	err = createBalance(ctx, tx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *UserRepository) GetByLogin(ctx context.Context, login string) (*models.User, error) {
@@ -53,3 +71,51 @@ func (r *UserRepository) UpdateLoginTime(ctx context.Context, userID string, tim
 	_, err := r.db.ExecContext(ctx, query, time, userID)
 	return err
 }
+
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	return err
+}
+
+// UpdatePassword replaces the password hash and revokes all sessions of the user.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrUserNotFound
+	}
+
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	_, err = tx.ExecContext(ctx, query, at, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	user := &models.User{}
+	query := `SELECT * FROM users WHERE id = $1`
+	err := r.db.GetContext(ctx, user, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -3,6 +3,7 @@ package postgr
 import (
 	"context"
 
+	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/models"
 	"github.com/jmoiron/sqlx"
 )
@@ -15,20 +16,51 @@ func NewWithdrawalRepository(db *sqlx.DB) *WithdrawalRepository {
 	return &WithdrawalRepository{db: db}
 }
 
-func (r *WithdrawalRepository) Create(ctx context.Context, w *models.Withdrawal) error {
-	query := `
+// Withdraw checks the balance and stores the withdrawal in one transaction.
+// The balance row is locked for the duration of the transaction, so concurrent
+// withdrawals of the same user are serialized and cannot overdraw the account.
+func (r *WithdrawalRepository) Withdraw(ctx context.Context, w *models.Withdrawal) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	balance, err := getUserBalance(ctx, tx, w.UserID, true)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM withdrawals WHERE user_id = $1 and order_number = $2)`
+	err = tx.GetContext(ctx, &exists, query, w.UserID, w.OrderNumber)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errs.ErrWithdrawalAlreadyProcessed
+	}
+
+	if balance.Current < w.Sum {
+		return errs.ErrBalanceInsufficient
+	}
+
+	query = `
 		INSERT INTO withdrawals (id, user_id, order_number, sum, processed_at)
 		VALUES ($1, $2, $3, $4, $5)
 	`
-	_, err := r.db.ExecContext(ctx, query, w.ID, w.UserID, w.OrderNumber, w.Sum, w.ProcessedAt)
-	return err
-}
+	_, err = tx.ExecContext(ctx, query, w.ID, w.UserID, w.OrderNumber, w.Sum, w.ProcessedAt)
+	if err != nil {
+		return err
+	}
 
-func (r *WithdrawalRepository) WithdrawalExists(ctx context.Context, userID string, orderNumber string) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS (SELECT 1 FROM withdrawals WHERE user_id = $1 and order_number = $2)`
-	err := r.db.GetContext(ctx, &exists, query, userID, orderNumber)
-	return exists, err
+	entry := models.NewLedgerEntry(w.UserID, models.LedgerEntryWithdrawal, w.OrderNumber, -w.Sum)
+	err = applyLedgerEntry(ctx, tx, entry, w.Sum)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *WithdrawalRepository) GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// AdminRepository runs administrative actions. Every change is written to
+// the audit log in the same transaction as the action itself.
+type AdminRepository struct {
+	db *sqlx.DB
+}
+
+func NewAdminRepository(db *sqlx.DB) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+// SearchUsers matches logins case-insensitively, LIKE ignores ASCII case in SQLite.
+func (r *AdminRepository) SearchUsers(ctx context.Context, login string, limit, offset int) ([]*models.User, error) {
+	var users []*models.User
+	query := `SELECT *
+			  FROM users
+			  WHERE login LIKE '%' || ? || '%' ESCAPE '\'
+			  ORDER BY created_at DESC
+			  LIMIT ? OFFSET ?`
+	err := r.db.SelectContext(ctx, &users, query, escapeLike(login), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *AdminRepository) UpdateRoles(
+	ctx context.Context, userID string, roles models.Roles, entry *models.AuditEntry) error {
+
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE users SET roles = ? WHERE id = ?`, roles, userID)
+		if err != nil {
+			return err
+		}
+		return userAffected(res)
+	})
+}
+
+// SetBlocked blocks the user when blockedAt is set and unblocks otherwise.
+// Blocking revokes all sessions of the user.
+func (r *AdminRepository) SetBlocked(
+	ctx context.Context, userID string, blockedAt *time.Time, entry *models.AuditEntry) error {
+
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE users SET blocked_at = ? WHERE id = ?`, utcPtr(blockedAt), userID)
+		if err != nil {
+			return err
+		}
+		err = userAffected(res)
+		if err != nil || blockedAt == nil {
+			return err
+		}
+		return revokeSessions(ctx, tx, userID, *blockedAt)
+	})
+}
+
+// UnlockLogin forgets the failed login attempts of the login.
+func (r *AdminRepository) UnlockLogin(ctx context.Context, login string, entry *models.AuditEntry) error {
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM login_attempts WHERE scope = ? AND key = ?`,
+			models.LoginAttemptScopeLogin, login)
+		return err
+	})
+}
+
+// RequeueOrder makes an order due for the next accrual poll, resets its
+// back-off and clears the manual review flag.
+func (r *AdminRepository) RequeueOrder(ctx context.Context, numberOrder string, entry *models.AuditEntry) error {
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		order, err := getOrder(ctx, tx, numberOrder)
+		if err != nil {
+			return err
+		}
+		if order.Status.IsFinal() {
+			return errs.ErrOrderAlreadyFinal
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE orders
+			 SET attempts = 0, rejections = 0, next_attempt_at = ?, locked_until = NULL, needs_review = 0
+			 WHERE number = ?`,
+			time.Now().UTC(), numberOrder)
+		return err
+	})
+}
+
+// AdjustBalance credits or debits the user balance. A debit larger than the
+// current balance fails with errs.ErrBalanceInsufficient.
+func (r *AdminRepository) AdjustBalance(
+	ctx context.Context, adj *models.BalanceAdjustment, entry *models.AuditEntry) error {
+
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		balance, err := getUserBalance(ctx, tx, adj.UserID)
+		if err != nil {
+			return err
+		}
+		if balance.Current+adj.Amount < 0 {
+			return errs.ErrBalanceInsufficient
+		}
+
+		query := `INSERT INTO balance_adjustments (id, user_id, amount, reason, ticket, operator_id, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, query,
+			adj.ID, adj.UserID, adj.Amount, adj.Reason, adj.Ticket, adj.OperatorID, utc(adj.CreatedAt))
+		if err != nil {
+			return err
+		}
+
+		ledger := models.NewLedgerEntry(adj.UserID, models.LedgerEntryAdjustment, "", adj.Amount)
+		return applyLedgerEntry(ctx, tx, ledger, 0)
+	})
+}
+
+func (r *AdminRepository) GetBalanceAdjustments(ctx context.Context, userID string) ([]*models.BalanceAdjustment, error) {
+	var adjustments []*models.BalanceAdjustment
+	query := `SELECT * FROM balance_adjustments WHERE user_id = ? ORDER BY created_at DESC, rowid DESC`
+	err := r.db.SelectContext(ctx, &adjustments, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
+
+func (r *AdminRepository) GetAuditLog(ctx context.Context, limit, offset int) ([]*models.AuditEntry, error) {
+	var entries []*models.AuditEntry
+	query := `SELECT * FROM audit_log ORDER BY created_at DESC, rowid DESC LIMIT ? OFFSET ?`
+	err := r.db.SelectContext(ctx, &entries, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *AdminRepository) withAudit(ctx context.Context, entry *models.AuditEntry, action func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = action(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_log (id, actor_id, action, target_type, target_id, details, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query,
+		entry.ID, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.Details, utc(entry.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
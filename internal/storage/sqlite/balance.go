@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type BalanceRepository struct {
+	db *sqlx.DB
+}
+
+func NewBalanceRepository(db *sqlx.DB) *BalanceRepository {
+	return &BalanceRepository{db: db}
+}
+
+func (r *BalanceRepository) GetUserBalance(ctx context.Context, userID string) (*models.Balance, error) {
+	return getUserBalance(ctx, r.db, userID)
+}
+
+func (r *BalanceRepository) GetBalanceMismatches(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	var mismatches []*models.BalanceMismatch
+	query := `
+		SELECT
+			b.user_id,
+			b.current,
+			b.withdrawn,
+			COALESCE(o.total_accrual, 0) + COALESCE(a.total_adjusted, 0) - COALESCE(w.total_withdrawn, 0) AS expected_current,
+			COALESCE(w.total_withdrawn, 0) AS expected_withdrawn
+		FROM balances b
+		LEFT JOIN (SELECT user_id, SUM(accrual) AS total_accrual FROM orders GROUP BY user_id) o ON o.user_id = b.user_id
+		LEFT JOIN (SELECT user_id, SUM(sum) AS total_withdrawn FROM withdrawals GROUP BY user_id) w ON w.user_id = b.user_id
+		LEFT JOIN (SELECT user_id, SUM(amount) AS total_adjusted FROM balance_adjustments GROUP BY user_id) a ON a.user_id = b.user_id
+	`
+	err := r.db.SelectContext(ctx, &mismatches, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Amounts are stored with numeric affinity and summed as floating point
+	// numbers, so they are compared after scanning into exact points.
+	n := 0
+	for _, m := range mismatches {
+		if m.Current != m.ExpectedCurrent || m.Withdrawn != m.ExpectedWithdrawn {
+			mismatches[n] = m
+			n++
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return mismatches[:n], nil
+}
+
+func (r *BalanceRepository) GetUserLedger(ctx context.Context, userID string) ([]*models.LedgerEntry, error) {
+	var entries []*models.LedgerEntry
+	query := `SELECT * FROM ledger_entries WHERE user_id = ? ORDER BY created_at DESC, rowid DESC`
+	err := r.db.SelectContext(ctx, &entries, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func getUserBalance(ctx context.Context, q sqlx.QueryerContext, userID string) (*models.Balance, error) {
+	balance := models.Balance{}
+	query := `SELECT user_id, current, withdrawn FROM balances WHERE user_id = ?`
+	err := sqlx.GetContext(ctx, q, &balance, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func createBalance(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO balances (user_id, current, withdrawn) VALUES (?, 0, 0)`, userID)
+	return err
+}
+
+// applyLedgerEntry moves the user balance by entry.Amount, records the entry
+// with the resulting running balance and adds withdrawn to the withdrawn total.
+// The sums are computed in Go from exact points rather than in SQL.
+func applyLedgerEntry(ctx context.Context, tx *sqlx.Tx, entry *models.LedgerEntry, withdrawn models.Points) error {
+	balance, err := getUserBalance(ctx, tx, entry.UserID)
+	if err != nil {
+		return err
+	}
+	entry.BalanceAfter = balance.Current + entry.Amount
+
+	query := `UPDATE balances SET current = ?, withdrawn = ? WHERE user_id = ?`
+	_, err = tx.ExecContext(ctx, query, entry.BalanceAfter, balance.Withdrawn+withdrawn, entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO ledger_entries (id, user_id, kind, order_number, amount, balance_after, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query,
+		entry.ID, entry.UserID, entry.Kind, entry.OrderNumber, entry.Amount, entry.BalanceAfter, utc(entry.CreatedAt))
+	return err
+}
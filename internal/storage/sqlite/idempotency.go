@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create stores the key unless it already exists and reports whether it was stored.
+func (r *IdempotencyRepository) Create(ctx context.Context, rec *models.IdempotencyRecord) (bool, error) {
+	query := `INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, locked_until)
+			  VALUES (?, ?, ?, ?, ?)
+			  ON CONFLICT (user_id, key) DO NOTHING`
+	res, err := r.db.ExecContext(ctx, query,
+		rec.UserID, rec.Key, rec.RequestHash, utc(rec.CreatedAt), utcPtr(rec.LockedUntil))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	rec := &models.IdempotencyRecord{}
+	query := `SELECT * FROM idempotency_keys WHERE user_id = ? AND key = ?`
+	err := r.db.GetContext(ctx, rec, query, userID, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Reclaim extends the lease of a key left without a response after its lease
+// expired and reports whether it was taken over.
+func (r *IdempotencyRepository) Reclaim(ctx context.Context, userID, key string, lockedUntil time.Time) (bool, error) {
+	query := `UPDATE idempotency_keys
+			  SET locked_until = ?
+			  WHERE user_id = ? AND key = ?
+			    AND status_code = 0
+			    AND (locked_until IS NULL OR locked_until <= ?)`
+	res, err := r.db.ExecContext(ctx, query, utc(lockedUntil), userID, key, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *IdempotencyRepository) SaveResponse(ctx context.Context, rec *models.IdempotencyRecord) error {
+	query := `UPDATE idempotency_keys
+			  SET status_code = ?, content_type = ?, body = ?
+			  WHERE user_id = ? AND key = ? AND locked_until = ?`
+	res, err := r.db.ExecContext(ctx, query,
+		rec.StatusCode, rec.ContentType, rec.Body, rec.UserID, rec.Key, utcPtr(rec.LockedUntil))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, userID, key string, lockedUntil time.Time) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ? AND locked_until = ?`
+	_, err := r.db.ExecContext(ctx, query, userID, key, utc(lockedUntil))
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < ?`
+	res, err := r.db.ExecContext(ctx, query, utc(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginAttemptRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Get(
+	ctx context.Context, scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+
+	attempt := &models.LoginAttempt{}
+	query := `SELECT * FROM login_attempts WHERE scope = ? AND key = ?`
+	err := r.db.GetContext(ctx, attempt, query, scope, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrLoginAttemptsNotFound
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// RegisterFailure increments the failure counter and returns the updated
+// record. Failures older than resetBefore are forgotten first.
+func (r *LoginAttemptRepository) RegisterFailure(
+	ctx context.Context, scope models.LoginAttemptScope, key string,
+	now, resetBefore time.Time) (*models.LoginAttempt, error) {
+
+	attempt := &models.LoginAttempt{}
+	query := `INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+			  VALUES (?, ?, 1, ?)
+			  ON CONFLICT (scope, key) DO UPDATE
+			  SET failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1
+			                      ELSE login_attempts.failures + 1 END,
+			      last_failure_at = excluded.last_failure_at
+			  RETURNING *`
+	err := r.db.GetContext(ctx, attempt, query, scope, key, utc(now), utc(resetBefore))
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (r *LoginAttemptRepository) Lock(
+	ctx context.Context, scope models.LoginAttemptScope, key string, until time.Time) error {
+
+	query := `UPDATE login_attempts SET locked_until = ? WHERE scope = ? AND key = ?`
+	_, err := r.db.ExecContext(ctx, query, utc(until), scope, key)
+	return err
+}
+
+func (r *LoginAttemptRepository) Reset(ctx context.Context, scope models.LoginAttemptScope, key string) error {
+	query := `DELETE FROM login_attempts WHERE scope = ? AND key = ?`
+	_, err := r.db.ExecContext(ctx, query, scope, key)
+	return err
+}
+
+// DeleteStale removes counters without recent failures and without an active lock.
+func (r *LoginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM login_attempts
+			  WHERE last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)`
+	res, err := r.db.ExecContext(ctx, query, utc(before), time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,8 @@
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    login TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    roles TEXT NOT NULL,
+    last_login_at TIMESTAMP
+);
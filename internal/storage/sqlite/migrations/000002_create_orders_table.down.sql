@@ -0,0 +1 @@
+DROP TABLE orders;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE orders (
+    number TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    status TEXT NOT NULL,
+    accrual DECIMAL(10,2),
+    uploaded_at TIMESTAMP NOT NULL
+);
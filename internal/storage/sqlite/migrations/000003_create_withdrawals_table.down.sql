@@ -0,0 +1 @@
+DROP TABLE withdrawals;
\ No newline at end of file
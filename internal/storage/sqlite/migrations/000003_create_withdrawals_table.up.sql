@@ -0,0 +1,7 @@
+CREATE TABLE withdrawals (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    order_number TEXT NOT NULL,
+    sum DECIMAL(10,2) NOT NULL CHECK (sum >= 0),
+    processed_at TIMESTAMP NOT NULL
+);
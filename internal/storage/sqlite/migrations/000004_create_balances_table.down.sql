@@ -0,0 +1 @@
+DROP TABLE balances;
\ No newline at end of file
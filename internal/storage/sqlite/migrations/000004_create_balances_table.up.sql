@@ -0,0 +1,5 @@
+CREATE TABLE balances (
+    user_id TEXT PRIMARY KEY REFERENCES users(id),
+    current DECIMAL(10,2) NOT NULL DEFAULT 0,
+    withdrawn DECIMAL(10,2) NOT NULL DEFAULT 0
+);
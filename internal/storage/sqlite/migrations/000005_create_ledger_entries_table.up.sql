@@ -0,0 +1,11 @@
+CREATE TABLE ledger_entries (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    kind TEXT NOT NULL,
+    order_number TEXT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    balance_after DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX ledger_entries_user_id_created_at_idx ON ledger_entries (user_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX orders_accrual_queue_idx;
+
+ALTER TABLE orders DROP COLUMN attempts;
+ALTER TABLE orders DROP COLUMN next_attempt_at;
+ALTER TABLE orders DROP COLUMN locked_until;
@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE orders ADD COLUMN locked_until TIMESTAMP;
+
+CREATE INDEX orders_accrual_queue_idx ON orders (next_attempt_at) WHERE status NOT IN ('INVALID', 'PROCESSED');
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN rejections;
+ALTER TABLE orders DROP COLUMN needs_review;
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN needs_review BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN rejections INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE balance_adjustments (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    amount DECIMAL(10,2) NOT NULL,
+    reason TEXT NOT NULL,
+    ticket TEXT NOT NULL,
+    operator_id TEXT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX balance_adjustments_user_id_idx ON balance_adjustments (user_id);
@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type OrderRepository struct {
+	db *sqlx.DB
+}
+
+func NewOrderRepository(db *sqlx.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
+	query := `INSERT INTO orders (number, user_id, status, accrual, uploaded_at, next_attempt_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, query,
+		order.Number, order.UserID, order.Status, order.Accrual, utc(order.UploadedAt), utc(order.NextAttemptAt))
+	if isUniqueViolation(err) {
+		existing, getErr := r.GetByNumber(ctx, order.Number)
+		if getErr != nil {
+			return getErr
+		}
+		if existing.UserID != order.UserID {
+			return errs.ErrOrderAlreadyUploadedByOtherUser
+		}
+		return errs.ErrOrderAlreadyUploadedByThisUser
+	}
+	return err
+}
+
+func (r *OrderRepository) GetByNumber(ctx context.Context, number string) (*models.Order, error) {
+	return getOrder(ctx, r.db, number)
+}
+
+func (r *OrderRepository) GetUserOrders(ctx context.Context, userID string) ([]*models.Order, error) {
+	var orders []*models.Order
+	query := `SELECT *
+			  FROM orders
+			  WHERE user_id = ?
+			  ORDER BY uploaded_at DESC`
+	err := r.db.SelectContext(ctx, &orders, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *OrderRepository) CountOrdersByStatus(ctx context.Context) (map[models.OrderStatus]int, error) {
+	var rows []struct {
+		Status models.OrderStatus `db:"status"`
+		Count  int                `db:"count"`
+	}
+	query := `SELECT status, count(*) AS count FROM orders GROUP BY status`
+	err := r.db.SelectContext(ctx, &rows, query)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[models.OrderStatus]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ClaimOrdersToAccrualUpdate leases up to limit due orders, earliest next
+// attempt first. Writers are serialized by SQLite, so a claimed order is not
+// handed out again until the lease expires.
+func (r *OrderRepository) ClaimOrdersToAccrualUpdate(
+	ctx context.Context, limit int, lease time.Duration) ([]*models.Order, error) {
+
+	now := time.Now().UTC()
+	var orders []*models.Order
+	query := `UPDATE orders
+			  SET locked_until = ?
+			  WHERE number IN (
+				  SELECT number
+				  FROM orders
+				  WHERE status NOT IN (?, ?)
+				    AND NOT needs_review
+				    AND next_attempt_at <= ?
+				    AND (locked_until IS NULL OR locked_until <= ?)
+				  ORDER BY next_attempt_at
+				  LIMIT ?
+			  )
+			  RETURNING *`
+	err := r.db.SelectContext(ctx, &orders, query,
+		now.Add(lease), models.StatusInvalid, models.StatusProcessed, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ScheduleAccrualRetry releases a claimed order after a failed poll and
+// postpones its next attempt by delay.
+func (r *OrderRepository) ScheduleAccrualRetry(ctx context.Context, numberOrder string, delay time.Duration) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1, rejections = 0, next_attempt_at = ?, locked_until = NULL
+			  WHERE number = ?`
+	return r.scheduleAccrual(ctx, query, delay, numberOrder)
+}
+
+// ScheduleAccrualRejection is ScheduleAccrualRetry for a poll whose response
+// was rejected; it also counts the rejection in a row.
+func (r *OrderRepository) ScheduleAccrualRejection(ctx context.Context, numberOrder string, delay time.Duration) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1, rejections = rejections + 1, next_attempt_at = ?, locked_until = NULL
+			  WHERE number = ?`
+	return r.scheduleAccrual(ctx, query, delay, numberOrder)
+}
+
+func (r *OrderRepository) scheduleAccrual(ctx context.Context, query string, delay time.Duration, numberOrder string) error {
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC().Add(delay), numberOrder)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+// HoldAccrualForReview releases a claimed order and excludes it from polling
+// until an administrator requeues it.
+func (r *OrderRepository) HoldAccrualForReview(ctx context.Context, numberOrder string) error {
+	query := `UPDATE orders
+			  SET attempts = attempts + 1, rejections = rejections + 1, needs_review = 1, locked_until = NULL
+			  WHERE number = ?`
+	res, err := r.db.ExecContext(ctx, query, numberOrder)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+// UpdateStatusAndAccural updates the order and credits the accrual difference
+// to the user balance and ledger in the same transaction. The update only
+// applies if the current status may move to the new one.
+func (r *OrderRepository) UpdateStatusAndAccural(
+	ctx context.Context,
+	numberOrder string,
+	status models.OrderStatus,
+	accrual *models.Points) error {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	order, err := getOrder(ctx, tx, numberOrder)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", errs.ErrOrderStatusTransition, order.Status, status)
+	}
+
+	query := `UPDATE orders
+			  SET status = ?, accrual = ?, attempts = 0, rejections = 0, next_attempt_at = ?, locked_until = NULL
+			  WHERE number = ?`
+	_, err = tx.ExecContext(ctx, query, status, accrual, time.Now().UTC(), numberOrder)
+	if err != nil {
+		return err
+	}
+
+	delta := accrualValue(accrual) - accrualValue(order.Accrual)
+	if delta != 0 {
+		entry := models.NewLedgerEntry(order.UserID, models.LedgerEntryAccrual, order.Number, delta)
+		err = applyLedgerEntry(ctx, tx, entry, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func getOrder(ctx context.Context, q sqlx.QueryerContext, number string) (*models.Order, error) {
+	order := &models.Order{}
+	err := sqlx.GetContext(ctx, q, order, `SELECT * FROM orders WHERE number = ?`, number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+func accrualValue(accrual *models.Points) models.Points {
+	if accrual == nil {
+		return 0
+	}
+	return *accrual
+}
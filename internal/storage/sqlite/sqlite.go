@@ -0,0 +1,142 @@
+// Package sqlite stores data in an embedded SQLite database, it is meant for
+// small deployments and demos where running Postgres is not worth it.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/XSAM/otelsql"
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Scheme is the DATABASE_URI prefix that selects SQLite, e.g. sqlite://gophermart.db.
+const Scheme = "sqlite://"
+
+// connectionParams enforce foreign keys like postgres does and take the write
+// lock when a transaction begins, so read-then-write transactions do not fail
+// on lock upgrade.
+const connectionParams = "_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"
+
+// NewConnection opens the database file from a sqlite:// DSN and applies the
+// migrations. SQLite has a single writer, so the pool is limited to one
+// connection; this also keeps :memory: databases shared by all queries.
+func NewConnection(ctx context.Context, dsn string) (*sqlx.DB, error) {
+	path, ok := strings.CutPrefix(dsn, Scheme)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%w: %q", errs.ErrDatabaseURIInvalid, dsn)
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	sqlDB, err := otelsql.Open("sqlite3", path+separator+connectionParams,
+		otelsql.WithAttributes(semconv.DBSystemNameSQLite))
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	db := sqlx.NewDb(sqlDB, "sqlite3")
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := runMigrations(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+func runMigrations(db *sqlx.DB) error {
+	driver, err := migratesqlite.WithInstance(db.DB, &migratesqlite.Config{})
+	if err != nil {
+		return err
+	}
+	sourceDriver, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	migr, err := migrate.NewWithInstance("iofs", sourceDriver, "sqlite3", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := migr.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
+
+// CheckMigrations fails unless the database schema is at the latest embedded
+// migration and the last migration finished cleanly.
+func CheckMigrations(ctx context.Context, db *sqlx.DB) error {
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var state struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err = db.GetContext(ctx, &state, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: no version", errs.ErrMigrationsNotApplied)
+	}
+	if err != nil {
+		return err
+	}
+	if state.Dirty {
+		return fmt.Errorf("%w: version %d", errs.ErrMigrationsDirty, state.Version)
+	}
+	if state.Version < latest {
+		return fmt.Errorf("%w: version %d of %d", errs.ErrMigrationsNotApplied, state.Version, latest)
+	}
+	return nil
+}
+
+func latestMigrationVersion() (uint, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, e := range entries {
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad migration file name %q: %w", e.Name(), err)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/Soliard/gophermart/internal/storage/storagetest"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := NewConnection(context.Background(), Scheme+filepath.Join(t.TempDir(), "gophermart.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *services.Repositories {
+		db := newTestDB(t)
+		return &services.Repositories{
+			Users:         NewUserRepository(db),
+			Orders:        NewOrderRepository(db),
+			Withdrawals:   NewWithdrawalRepository(db),
+			Balance:       NewBalanceRepository(db),
+			Idempotency:   NewIdempotencyRepository(db),
+			Sessions:      NewSessionRepository(db),
+			Admin:         NewAdminRepository(db),
+			LoginAttempts: NewLoginAttemptRepository(db),
+			TwoFactor:     NewTwoFactorRepository(db),
+		}
+	})
+}
+
+func TestNewConnection(t *testing.T) {
+	t.Run("миграции применены", func(t *testing.T) {
+		db := newTestDB(t)
+		require.NoError(t, CheckMigrations(context.Background(), db))
+	})
+
+	t.Run("строка подключения без схемы sqlite", func(t *testing.T) {
+		_, err := NewConnection(context.Background(), "postgres://localhost/gophermart")
+		require.ErrorIs(t, err, errs.ErrDatabaseURIInvalid)
+	})
+}
@@ -0,0 +1,17 @@
+package sqlite
+
+import "time"
+
+// SQLite has no timestamp type, the driver stores times as text. All times are
+// written in UTC so that text comparison and ordering match time order.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
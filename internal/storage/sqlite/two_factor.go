@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SetPendingSecret stores a new secret that is not used for login until it
+// is confirmed. It fails if two-factor authentication is already enabled.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	query := `UPDATE users SET totp_secret = ?, totp_last_step = NULL
+			  WHERE id = ? AND totp_enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes.
+func (r *TwoFactorRepository) Enable(
+	ctx context.Context, userID string, at time.Time, step int64, recoveryCodeHashes []string) error {
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET totp_enabled_at = ?, totp_last_step = ?
+			  WHERE id = ? AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, utc(at), step, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrTwoFactorAlreadyEnabled
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the time step of an accepted code. It reports false if the
+// step or a later one was already used, which rejects replayed codes.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = ?
+			  WHERE id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)`
+	res, err := r.db.ExecContext(ctx, query, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether it existed.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = ?
+			  WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, utc(at), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
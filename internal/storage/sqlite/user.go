@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type UserRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, u *models.User) error {
+	query := `
+		INSERT INTO users (id, login, password_hash, created_at, roles, last_login_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query,
+		u.ID, u.Login, u.PasswordHash, utc(u.CreatedAt), u.Roles, utcPtr(u.LastLoginAt))
+	if isUniqueViolation(err) {
+		return errs.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	err = createBalance(ctx, tx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserRepository) GetByLogin(ctx context.Context, login string) (*models.User, error) {
+	return r.get(ctx, `SELECT * FROM users WHERE login = ?`, login)
+}
+
+func (r *UserRepository) UserExists(ctx context.Context, login string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE login = ?)`
+	err := r.db.GetContext(ctx, &exists, query, login)
+	return exists, err
+}
+
+func (r *UserRepository) UpdateLoginTime(ctx context.Context, userID string, time time.Time) error {
+	query := `UPDATE users SET last_login_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, utc(time), userID)
+	return err
+}
+
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	return err
+}
+
+// UpdatePassword replaces the password hash and revokes all sessions of the user.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	err = userAffected(res)
+	if err != nil {
+		return err
+	}
+
+	err = revokeSessions(ctx, tx, userID, at)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.get(ctx, `SELECT * FROM users WHERE id = ?`, id)
+}
+
+func (r *UserRepository) get(ctx context.Context, query string, arg string) (*models.User, error) {
+	user := &models.User{}
+	err := r.db.GetContext(ctx, user, query, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func userAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrUserNotFound
+	}
+	return nil
+}
+
+func revokeSessions(ctx context.Context, tx *sqlx.Tx, userID string, at time.Time) error {
+	query := `UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	_, err := tx.ExecContext(ctx, query, utc(at), userID)
+	return err
+}
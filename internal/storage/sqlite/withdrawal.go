@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type WithdrawalRepository struct {
+	db *sqlx.DB
+}
+
+func NewWithdrawalRepository(db *sqlx.DB) *WithdrawalRepository {
+	return &WithdrawalRepository{db: db}
+}
+
+// Withdraw checks the balance and stores the withdrawal in one transaction.
+// Transactions take the database write lock when they begin, so concurrent
+// withdrawals cannot overdraw the account.
+func (r *WithdrawalRepository) Withdraw(ctx context.Context, w *models.Withdrawal) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	balance, err := getUserBalance(ctx, tx, w.UserID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM withdrawals WHERE user_id = ? AND order_number = ?)`
+	err = tx.GetContext(ctx, &exists, query, w.UserID, w.OrderNumber)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errs.ErrWithdrawalAlreadyProcessed
+	}
+
+	if balance.Current < w.Sum {
+		return errs.ErrBalanceInsufficient
+	}
+
+	query = `
+		INSERT INTO withdrawals (id, user_id, order_number, sum, processed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, query, w.ID, w.UserID, w.OrderNumber, w.Sum, utc(w.ProcessedAt))
+	if err != nil {
+		return err
+	}
+
+	entry := models.NewLedgerEntry(w.UserID, models.LedgerEntryWithdrawal, w.OrderNumber, -w.Sum)
+	err = applyLedgerEntry(ctx, tx, entry, w.Sum)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *WithdrawalRepository) GetWithdrawals(ctx context.Context, userID string) ([]*models.Withdrawal, error) {
+	var withdrawals []*models.Withdrawal
+	query := `SELECT * FROM withdrawals WHERE user_id = ? ORDER BY processed_at DESC, rowid DESC`
+	err := r.db.SelectContext(ctx, &withdrawals, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
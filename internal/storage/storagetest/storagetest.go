@@ -0,0 +1,819 @@
+// Package storagetest is a contract test suite for repository
+// implementations. Every storage backend runs it, so the backends stay
+// interchangeable for the services.
+package storagetest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/errs"
+	"github.com/Soliard/gophermart/internal/models"
+	"github.com/Soliard/gophermart/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns repositories over an empty storage.
+type Factory func(t *testing.T) *services.Repositories
+
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos) })
+	t.Run("Orders", func(t *testing.T) { testOrders(t, newRepos) })
+	t.Run("Withdrawals", func(t *testing.T) { testWithdrawals(t, newRepos) })
+	t.Run("Balance", func(t *testing.T) { testBalance(t, newRepos) })
+	t.Run("Idempotency", func(t *testing.T) { testIdempotency(t, newRepos) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newRepos) })
+	t.Run("LoginAttempts", func(t *testing.T) { testLoginAttempts(t, newRepos) })
+	t.Run("TwoFactor", func(t *testing.T) { testTwoFactor(t, newRepos) })
+	t.Run("Admin", func(t *testing.T) { testAdmin(t, newRepos) })
+}
+
+func testUsers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("созданный пользователь находится по логину и id", func(t *testing.T) {
+		repos := newRepos(t)
+		u := models.NewUser("alice", "hash")
+		require.NoError(t, repos.Users.Create(ctx, u))
+
+		byLogin, err := repos.Users.GetByLogin(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, u.ID, byLogin.ID)
+		require.Equal(t, "hash", byLogin.PasswordHash)
+		require.Equal(t, u.Roles, byLogin.Roles)
+
+		byID, err := repos.Users.GetByID(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, "alice", byID.Login)
+
+		exists, err := repos.Users.UserExists(ctx, "alice")
+		require.NoError(t, err)
+		require.True(t, exists)
+	})
+
+	t.Run("неизвестный пользователь", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Users.GetByLogin(ctx, "nobody")
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+
+		_, err = repos.Users.GetByID(ctx, models.NewUser("nobody", "hash").ID)
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+
+		exists, err := repos.Users.UserExists(ctx, "nobody")
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+
+	t.Run("повторный логин отклоняется", func(t *testing.T) {
+		repos := newRepos(t)
+		require.NoError(t, repos.Users.Create(ctx, models.NewUser("alice", "hash")))
+
+		err := repos.Users.Create(ctx, models.NewUser("alice", "other"))
+		require.ErrorIs(t, err, errs.ErrUserAlreadyExists)
+	})
+
+	t.Run("новый пользователь получает нулевой баланс", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Zero(t, balance.Current)
+		require.Zero(t, balance.Withdrawn)
+	})
+
+	t.Run("смена пароля обновляет хеш", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+
+		require.NoError(t, repos.Users.UpdatePassword(ctx, u.ID, "new-hash", time.Now().UTC()))
+
+		got, err := repos.Users.GetByID(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, "new-hash", got.PasswordHash)
+	})
+}
+
+func testOrders(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("созданный заказ находится по номеру", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+
+		got, err := repos.Orders.GetByNumber(ctx, "12345678903")
+		require.NoError(t, err)
+		require.Equal(t, u.ID, got.UserID)
+		require.Equal(t, models.StatusNew, got.Status)
+		require.Nil(t, got.Accrual)
+	})
+
+	t.Run("неизвестный заказ", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Orders.GetByNumber(ctx, "12345678903")
+		require.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+		err = repos.Orders.UpdateStatusAndAccural(ctx, "12345678903", models.StatusInvalid, nil)
+		require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	})
+
+	t.Run("повторная загрузка заказа", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "alice")
+		bob := createUser(t, repos, "bob")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", alice.ID)))
+
+		err := repos.Orders.Create(ctx, models.NewOrder("12345678903", alice.ID))
+		require.ErrorIs(t, err, errs.ErrOrderAlreadyUploadedByThisUser)
+
+		err = repos.Orders.Create(ctx, models.NewOrder("12345678903", bob.ID))
+		require.ErrorIs(t, err, errs.ErrOrderAlreadyUploadedByOtherUser)
+	})
+
+	t.Run("заказы пользователя от новых к старым", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "alice")
+		bob := createUser(t, repos, "bob")
+
+		older := models.NewOrder("12345678903", alice.ID)
+		older.UploadedAt = older.UploadedAt.Add(-time.Hour)
+		require.NoError(t, repos.Orders.Create(ctx, older))
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("2377225624", alice.ID)))
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("4561261212345467", bob.ID)))
+
+		orders, err := repos.Orders.GetUserOrders(ctx, alice.ID)
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		require.Equal(t, "2377225624", orders[0].Number)
+		require.Equal(t, "12345678903", orders[1].Number)
+	})
+
+	t.Run("начисление зачисляется на баланс", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+
+		accrual := models.Points(50000)
+		err := repos.Orders.UpdateStatusAndAccural(ctx, "12345678903", models.StatusProcessed, &accrual)
+		require.NoError(t, err)
+
+		got, err := repos.Orders.GetByNumber(ctx, "12345678903")
+		require.NoError(t, err)
+		require.Equal(t, models.StatusProcessed, got.Status)
+		require.Equal(t, accrual, *got.Accrual)
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, accrual, balance.Current)
+	})
+
+	t.Run("финальный статус не меняется", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+		require.NoError(t, repos.Orders.UpdateStatusAndAccural(ctx, "12345678903", models.StatusInvalid, nil))
+
+		accrual := models.Points(100)
+		err := repos.Orders.UpdateStatusAndAccural(ctx, "12345678903", models.StatusProcessed, &accrual)
+		require.ErrorIs(t, err, errs.ErrOrderStatusTransition)
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Zero(t, balance.Current)
+	})
+
+	t.Run("захваченный заказ не выдается повторно", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+
+		claimed, err := repos.Orders.ClaimOrdersToAccrualUpdate(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+
+		claimed, err = repos.Orders.ClaimOrdersToAccrualUpdate(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+	})
+
+	t.Run("отказы подряд сбрасываются другим результатом опроса", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+
+		claim := func() *models.Order {
+			claimed, err := repos.Orders.ClaimOrdersToAccrualUpdate(ctx, 10, time.Minute)
+			require.NoError(t, err)
+			require.Len(t, claimed, 1)
+			return claimed[0]
+		}
+
+		claim()
+		require.NoError(t, repos.Orders.ScheduleAccrualRejection(ctx, "12345678903", 0))
+		claim()
+		require.NoError(t, repos.Orders.ScheduleAccrualRejection(ctx, "12345678903", 0))
+		o := claim()
+		require.Equal(t, 2, o.Attempts)
+		require.Equal(t, 2, o.Rejections)
+
+		require.NoError(t, repos.Orders.ScheduleAccrualRetry(ctx, "12345678903", 0))
+		o = claim()
+		require.Equal(t, 3, o.Attempts)
+		require.Zero(t, o.Rejections)
+
+		err := repos.Orders.ScheduleAccrualRejection(ctx, "2377225624", 0)
+		require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	})
+
+	t.Run("заказ на ручной проверке не выдается до перепостановки", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		require.NoError(t, repos.Orders.Create(ctx, models.NewOrder("12345678903", u.ID)))
+
+		require.NoError(t, repos.Orders.HoldAccrualForReview(ctx, "12345678903"))
+		claimed, err := repos.Orders.ClaimOrdersToAccrualUpdate(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+
+		entry := models.NewAuditEntry(u.ID, models.AuditOrderRequeued, models.AuditTargetOrder, "12345678903", nil)
+		require.NoError(t, repos.Admin.RequeueOrder(ctx, "12345678903", entry))
+		claimed, err = repos.Orders.ClaimOrdersToAccrualUpdate(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.False(t, claimed[0].NeedsReview)
+		require.Zero(t, claimed[0].Attempts)
+		require.Zero(t, claimed[0].Rejections)
+
+		err = repos.Orders.HoldAccrualForReview(ctx, "2377225624")
+		require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	})
+}
+
+func testWithdrawals(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("списание уменьшает баланс", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", 50000)
+
+		require.NoError(t, repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 20000)))
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.Points(30000), balance.Current)
+		require.Equal(t, models.Points(20000), balance.Withdrawn)
+
+		withdrawals, err := repos.Withdrawals.GetWithdrawals(ctx, u.ID)
+		require.NoError(t, err)
+		require.Len(t, withdrawals, 1)
+		require.Equal(t, "2377225624", withdrawals[0].OrderNumber)
+		require.Equal(t, models.Points(20000), withdrawals[0].Sum)
+	})
+
+	t.Run("недостаточно баллов", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", 100)
+
+		err := repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 101))
+		require.ErrorIs(t, err, errs.ErrBalanceInsufficient)
+
+		withdrawals, err := repos.Withdrawals.GetWithdrawals(ctx, u.ID)
+		require.NoError(t, err)
+		require.Empty(t, withdrawals)
+	})
+
+	t.Run("повторное списание по тому же заказу", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", 50000)
+		require.NoError(t, repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 100)))
+
+		err := repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 100))
+		require.ErrorIs(t, err, errs.ErrWithdrawalAlreadyProcessed)
+	})
+
+	t.Run("параллельные списания не уводят баланс в минус", func(t *testing.T) {
+		const (
+			attempts = 20
+			balance  = models.Points(10000)
+			sum      = models.Points(1000)
+		)
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", balance)
+
+		var wg sync.WaitGroup
+		results := make(chan error, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(order string) {
+				defer wg.Done()
+				results <- repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, order, sum))
+			}(strconv.Itoa(1000 + i))
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			require.ErrorIs(t, err, errs.ErrBalanceInsufficient)
+		}
+		require.Equal(t, int(balance/sum), succeeded)
+
+		got, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, got.Current, models.Points(0))
+		require.Equal(t, balance-sum*models.Points(succeeded), got.Current)
+
+		withdrawals, err := repos.Withdrawals.GetWithdrawals(ctx, u.ID)
+		require.NoError(t, err)
+		require.Len(t, withdrawals, succeeded)
+	})
+
+	t.Run("списание у неизвестного пользователя", func(t *testing.T) {
+		repos := newRepos(t)
+
+		err := repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(models.NewUser("nobody", "hash").ID, "2377225624", 100))
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+}
+
+func testBalance(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("баланс неизвестного пользователя", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Balance.GetUserBalance(ctx, models.NewUser("nobody", "hash").ID)
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+
+	t.Run("история операций с остатком", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", 50000)
+		require.NoError(t, repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 20000)))
+
+		ledger, err := repos.Balance.GetUserLedger(ctx, u.ID)
+		require.NoError(t, err)
+		require.Len(t, ledger, 2)
+		require.Equal(t, models.Points(-20000), ledger[0].Amount)
+		require.Equal(t, models.Points(30000), ledger[0].BalanceAfter)
+		require.Equal(t, models.Points(50000), ledger[1].Amount)
+		require.Equal(t, models.Points(50000), ledger[1].BalanceAfter)
+	})
+
+	t.Run("согласованный баланс без расхождений", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUserWithPoints(t, repos, "alice", 50000)
+		require.NoError(t, repos.Withdrawals.Withdraw(ctx, models.NewWithdrawal(u.ID, "2377225624", 20000)))
+
+		mismatches, err := repos.Balance.GetBalanceMismatches(ctx)
+		require.NoError(t, err)
+		require.Empty(t, mismatches)
+	})
+}
+
+func testIdempotency(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("ключ с действующей арендой не перехватывается", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		created, err := repos.Idempotency.Create(ctx, models.NewIdempotencyRecord(u.ID, "key-1", "hash", time.Minute))
+		require.NoError(t, err)
+		require.True(t, created)
+
+		created, err = repos.Idempotency.Create(ctx, models.NewIdempotencyRecord(u.ID, "key-1", "hash", time.Minute))
+		require.NoError(t, err)
+		require.False(t, created)
+
+		reclaimed, err := repos.Idempotency.Reclaim(ctx, u.ID, "key-1", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.False(t, reclaimed)
+	})
+
+	t.Run("ключ с истекшей арендой перехватывается один раз", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		_, err := repos.Idempotency.Create(ctx, models.NewIdempotencyRecord(u.ID, "key-1", "hash", -time.Minute))
+		require.NoError(t, err)
+
+		reclaimed, err := repos.Idempotency.Reclaim(ctx, u.ID, "key-1", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.True(t, reclaimed)
+
+		reclaimed, err = repos.Idempotency.Reclaim(ctx, u.ID, "key-1", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.False(t, reclaimed)
+
+		rec, err := repos.Idempotency.Get(ctx, u.ID, "key-1")
+		require.NoError(t, err)
+		require.True(t, rec.Locked(time.Now()))
+	})
+
+	t.Run("завершенный ключ не перехватывается", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		rec := models.NewIdempotencyRecord(u.ID, "key-1", "hash", -time.Minute)
+		_, err := repos.Idempotency.Create(ctx, rec)
+		require.NoError(t, err)
+		rec.StatusCode = 202
+		rec.Body = []byte("accepted")
+		require.NoError(t, repos.Idempotency.SaveResponse(ctx, rec))
+
+		reclaimed, err := repos.Idempotency.Reclaim(ctx, u.ID, "key-1", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.False(t, reclaimed)
+
+		got, err := repos.Idempotency.Get(ctx, u.ID, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, 202, got.StatusCode)
+		require.Equal(t, []byte("accepted"), got.Body)
+	})
+
+	t.Run("запрос без аренды не сохраняет и не удаляет ключ", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		stale := models.NewIdempotencyRecord(u.ID, "key-1", "hash", -time.Minute)
+		_, err := repos.Idempotency.Create(ctx, stale)
+		require.NoError(t, err)
+
+		lockedUntil := models.IdempotencyLeaseUntil(time.Now(), time.Minute)
+		reclaimed, err := repos.Idempotency.Reclaim(ctx, u.ID, "key-1", lockedUntil)
+		require.NoError(t, err)
+		require.True(t, reclaimed)
+
+		stale.StatusCode = 500
+		err = repos.Idempotency.SaveResponse(ctx, stale)
+		require.ErrorIs(t, err, errs.ErrIdempotencyLeaseLost)
+		require.NoError(t, repos.Idempotency.Delete(ctx, u.ID, "key-1", *stale.LockedUntil))
+
+		got, err := repos.Idempotency.Get(ctx, u.ID, "key-1")
+		require.NoError(t, err)
+		require.False(t, got.Completed())
+
+		owner := *got
+		owner.StatusCode = 202
+		require.NoError(t, repos.Idempotency.SaveResponse(ctx, &owner))
+		got, err = repos.Idempotency.Get(ctx, u.ID, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, 202, got.StatusCode)
+	})
+
+	t.Run("владелец аренды освобождает ключ", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		rec := models.NewIdempotencyRecord(u.ID, "key-1", "hash", time.Minute)
+		_, err := repos.Idempotency.Create(ctx, rec)
+		require.NoError(t, err)
+
+		require.NoError(t, repos.Idempotency.Delete(ctx, u.ID, "key-1", *rec.LockedUntil))
+		_, err = repos.Idempotency.Get(ctx, u.ID, "key-1")
+		require.ErrorIs(t, err, errs.ErrIdempotencyKeyNotFound)
+	})
+}
+
+func testSessions(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("ротация заменяет refresh-токен один раз", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		s := models.NewSession(u.ID, "hash-1", time.Hour)
+		require.NoError(t, repos.Sessions.Create(ctx, s))
+
+		got, err := repos.Sessions.GetByRefreshTokenHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.Equal(t, s.ID, got.ID)
+		require.Equal(t, u.ID, got.UserID)
+		require.True(t, got.Active(time.Now()))
+
+		expiresAt := time.Now().Add(2 * time.Hour)
+		require.NoError(t, repos.Sessions.Rotate(ctx, s.ID, "hash-1", "hash-2", expiresAt))
+		err = repos.Sessions.Rotate(ctx, s.ID, "hash-1", "hash-3", expiresAt)
+		require.ErrorIs(t, err, errs.ErrSessionNotFound)
+
+		_, err = repos.Sessions.GetByRefreshTokenHash(ctx, "hash-1")
+		require.ErrorIs(t, err, errs.ErrSessionNotFound)
+		got, err = repos.Sessions.GetByRefreshTokenHash(ctx, "hash-2")
+		require.NoError(t, err)
+		require.WithinDuration(t, expiresAt, got.ExpiresAt, time.Millisecond)
+	})
+
+	t.Run("отозванная сессия не ротируется", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		s := models.NewSession(u.ID, "hash-1", time.Hour)
+		require.NoError(t, repos.Sessions.Create(ctx, s))
+
+		revokedAt := time.Now().UTC()
+		require.NoError(t, repos.Sessions.Revoke(ctx, s.ID, revokedAt))
+		require.NoError(t, repos.Sessions.Revoke(ctx, s.ID, revokedAt.Add(time.Hour)))
+
+		got, err := repos.Sessions.GetByID(ctx, s.ID)
+		require.NoError(t, err)
+		require.False(t, got.Active(time.Now()))
+		require.WithinDuration(t, revokedAt, *got.RevokedAt, time.Millisecond)
+
+		err = repos.Sessions.Rotate(ctx, s.ID, "hash-1", "hash-2", time.Now().Add(time.Hour))
+		require.ErrorIs(t, err, errs.ErrSessionNotFound)
+	})
+
+	t.Run("отзыв всех сессий пользователя", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "alice")
+		bob := createUser(t, repos, "bob")
+		first := models.NewSession(alice.ID, "hash-1", time.Hour)
+		second := models.NewSession(alice.ID, "hash-2", time.Hour)
+		other := models.NewSession(bob.ID, "hash-3", time.Hour)
+		for _, s := range []*models.Session{first, second, other} {
+			require.NoError(t, repos.Sessions.Create(ctx, s))
+		}
+
+		require.NoError(t, repos.Sessions.RevokeAllForUser(ctx, alice.ID, time.Now().UTC()))
+
+		for _, s := range []*models.Session{first, second} {
+			got, err := repos.Sessions.GetByID(ctx, s.ID)
+			require.NoError(t, err)
+			require.False(t, got.Active(time.Now()))
+		}
+		got, err := repos.Sessions.GetByID(ctx, other.ID)
+		require.NoError(t, err)
+		require.True(t, got.Active(time.Now()))
+	})
+
+	t.Run("неизвестная сессия", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Sessions.GetByID(ctx, models.NewSession("", "", 0).ID)
+		require.ErrorIs(t, err, errs.ErrSessionNotFound)
+	})
+}
+
+func testLoginAttempts(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	scope := models.LoginAttemptScopeLogin
+
+	t.Run("неудачные попытки считаются подряд", func(t *testing.T) {
+		repos := newRepos(t)
+		now := time.Now().UTC()
+
+		for i := 1; i <= 3; i++ {
+			attempt, err := repos.LoginAttempts.RegisterFailure(ctx, scope, "alice", now, now.Add(-time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, i, attempt.Failures)
+		}
+
+		attempt, err := repos.LoginAttempts.Get(ctx, scope, "alice")
+		require.NoError(t, err)
+		require.Equal(t, 3, attempt.Failures)
+
+		_, err = repos.LoginAttempts.Get(ctx, models.LoginAttemptScopeIP, "alice")
+		require.ErrorIs(t, err, errs.ErrLoginAttemptsNotFound)
+	})
+
+	t.Run("старые попытки забываются", func(t *testing.T) {
+		repos := newRepos(t)
+		now := time.Now().UTC()
+
+		_, err := repos.LoginAttempts.RegisterFailure(ctx, scope, "alice", now.Add(-2*time.Hour), now.Add(-3*time.Hour))
+		require.NoError(t, err)
+		attempt, err := repos.LoginAttempts.RegisterFailure(ctx, scope, "alice", now, now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, attempt.Failures)
+	})
+
+	t.Run("блокировка и сброс", func(t *testing.T) {
+		repos := newRepos(t)
+		now := time.Now().UTC()
+		_, err := repos.LoginAttempts.RegisterFailure(ctx, scope, "alice", now, now.Add(-time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, repos.LoginAttempts.Lock(ctx, scope, "alice", now.Add(time.Minute)))
+		attempt, err := repos.LoginAttempts.Get(ctx, scope, "alice")
+		require.NoError(t, err)
+		require.True(t, attempt.Locked(now))
+
+		require.NoError(t, repos.LoginAttempts.Reset(ctx, scope, "alice"))
+		_, err = repos.LoginAttempts.Get(ctx, scope, "alice")
+		require.ErrorIs(t, err, errs.ErrLoginAttemptsNotFound)
+	})
+
+	t.Run("устаревшие счетчики удаляются, активная блокировка остается", func(t *testing.T) {
+		repos := newRepos(t)
+		old := time.Now().UTC().Add(-2 * time.Hour)
+		for _, key := range []string{"alice", "bob"} {
+			_, err := repos.LoginAttempts.RegisterFailure(ctx, scope, key, old, old.Add(-time.Hour))
+			require.NoError(t, err)
+		}
+		require.NoError(t, repos.LoginAttempts.Lock(ctx, scope, "bob", time.Now().Add(time.Hour)))
+
+		deleted, err := repos.LoginAttempts.DeleteStale(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+
+		_, err = repos.LoginAttempts.Get(ctx, scope, "alice")
+		require.ErrorIs(t, err, errs.ErrLoginAttemptsNotFound)
+		_, err = repos.LoginAttempts.Get(ctx, scope, "bob")
+		require.NoError(t, err)
+	})
+}
+
+func testTwoFactor(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	enable := func(t *testing.T, repos *services.Repositories, userID string, step int64) {
+		t.Helper()
+		require.NoError(t, repos.TwoFactor.SetPendingSecret(ctx, userID, "secret"))
+		require.NoError(t, repos.TwoFactor.Enable(ctx, userID, time.Now().UTC(), step, []string{"code-1", "code-2"}))
+	}
+
+	t.Run("подключение и повторная настройка", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+
+		err := repos.TwoFactor.Enable(ctx, u.ID, time.Now().UTC(), 100, nil)
+		require.ErrorIs(t, err, errs.ErrTwoFactorAlreadyEnabled)
+
+		enable(t, repos, u.ID, 100)
+		got, err := repos.Users.GetByID(ctx, u.ID)
+		require.NoError(t, err)
+		require.True(t, got.TwoFactorEnabled())
+
+		err = repos.TwoFactor.SetPendingSecret(ctx, u.ID, "other")
+		require.ErrorIs(t, err, errs.ErrTwoFactorAlreadyEnabled)
+		err = repos.TwoFactor.Enable(ctx, u.ID, time.Now().UTC(), 101, nil)
+		require.ErrorIs(t, err, errs.ErrTwoFactorAlreadyEnabled)
+	})
+
+	t.Run("шаг TOTP нельзя использовать повторно", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		enable(t, repos, u.ID, 100)
+
+		used, err := repos.TwoFactor.UseStep(ctx, u.ID, 100)
+		require.NoError(t, err)
+		require.False(t, used)
+
+		used, err = repos.TwoFactor.UseStep(ctx, u.ID, 101)
+		require.NoError(t, err)
+		require.True(t, used)
+
+		for _, step := range []int64{101, 99} {
+			used, err = repos.TwoFactor.UseStep(ctx, u.ID, step)
+			require.NoError(t, err)
+			require.False(t, used)
+		}
+	})
+
+	t.Run("код восстановления одноразовый", func(t *testing.T) {
+		repos := newRepos(t)
+		u := createUser(t, repos, "alice")
+		enable(t, repos, u.ID, 100)
+
+		used, err := repos.TwoFactor.UseRecoveryCode(ctx, u.ID, "code-1", time.Now().UTC())
+		require.NoError(t, err)
+		require.True(t, used)
+
+		used, err = repos.TwoFactor.UseRecoveryCode(ctx, u.ID, "code-1", time.Now().UTC())
+		require.NoError(t, err)
+		require.False(t, used)
+
+		used, err = repos.TwoFactor.UseRecoveryCode(ctx, u.ID, "unknown", time.Now().UTC())
+		require.NoError(t, err)
+		require.False(t, used)
+	})
+}
+
+func testAdmin(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("корректировка баланса записывается в журнал", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := createUser(t, repos, "admin")
+		u := createUserWithPoints(t, repos, "alice", 10000)
+
+		adj := models.NewBalanceAdjustment(u.ID, -2550, "chargeback", "TICKET-1", admin.ID)
+		entry := models.NewAuditEntry(admin.ID, models.AuditBalanceAdjust, models.AuditTargetUser, u.ID, nil)
+		require.NoError(t, repos.Admin.AdjustBalance(ctx, adj, entry))
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.Points(7450), balance.Current)
+
+		adjustments, err := repos.Admin.GetBalanceAdjustments(ctx, u.ID)
+		require.NoError(t, err)
+		require.Len(t, adjustments, 1)
+		require.Equal(t, models.Points(-2550), adjustments[0].Amount)
+		require.Equal(t, "TICKET-1", adjustments[0].Ticket)
+
+		ledger, err := repos.Balance.GetUserLedger(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.LedgerEntryAdjustment, ledger[0].Kind)
+		require.Equal(t, models.Points(7450), ledger[0].BalanceAfter)
+
+		audit, err := repos.Admin.GetAuditLog(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, audit, 1)
+		require.Equal(t, entry.ID, audit[0].ID)
+	})
+
+	t.Run("списание больше баланса не проходит и не попадает в журнал", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := createUser(t, repos, "admin")
+		u := createUserWithPoints(t, repos, "alice", 10000)
+
+		adj := models.NewBalanceAdjustment(u.ID, -10001, "chargeback", "TICKET-1", admin.ID)
+		entry := models.NewAuditEntry(admin.ID, models.AuditBalanceAdjust, models.AuditTargetUser, u.ID, nil)
+		err := repos.Admin.AdjustBalance(ctx, adj, entry)
+		require.ErrorIs(t, err, errs.ErrBalanceInsufficient)
+
+		balance, err := repos.Balance.GetUserBalance(ctx, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.Points(10000), balance.Current)
+		adjustments, err := repos.Admin.GetBalanceAdjustments(ctx, u.ID)
+		require.NoError(t, err)
+		require.Empty(t, adjustments)
+		audit, err := repos.Admin.GetAuditLog(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Empty(t, audit)
+	})
+
+	t.Run("блокировка отзывает сессии", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := createUser(t, repos, "admin")
+		u := createUser(t, repos, "alice")
+		s := models.NewSession(u.ID, "hash-1", time.Hour)
+		require.NoError(t, repos.Sessions.Create(ctx, s))
+
+		blockedAt := time.Now().UTC()
+		entry := models.NewAuditEntry(admin.ID, models.AuditUserBlocked, models.AuditTargetUser, u.ID, nil)
+		require.NoError(t, repos.Admin.SetBlocked(ctx, u.ID, &blockedAt, entry))
+
+		got, err := repos.Users.GetByID(ctx, u.ID)
+		require.NoError(t, err)
+		require.True(t, got.Blocked())
+		session, err := repos.Sessions.GetByID(ctx, s.ID)
+		require.NoError(t, err)
+		require.False(t, session.Active(time.Now()))
+
+		entry = models.NewAuditEntry(admin.ID, models.AuditUserUnblocked, models.AuditTargetUser, u.ID, nil)
+		require.NoError(t, repos.Admin.SetBlocked(ctx, u.ID, nil, entry))
+		got, err = repos.Users.GetByID(ctx, u.ID)
+		require.NoError(t, err)
+		require.False(t, got.Blocked())
+
+		audit, err := repos.Admin.GetAuditLog(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, audit, 2)
+
+		entry = models.NewAuditEntry(admin.ID, models.AuditUserBlocked, models.AuditTargetUser, "", nil)
+		err = repos.Admin.SetBlocked(ctx, models.NewUser("nobody", "hash").ID, &blockedAt, entry)
+		require.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+
+	t.Run("разблокировка входа сбрасывает только счетчик логина", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := createUser(t, repos, "admin")
+		now := time.Now().UTC()
+		_, err := repos.LoginAttempts.RegisterFailure(ctx, models.LoginAttemptScopeLogin, "alice", now, now.Add(-time.Hour))
+		require.NoError(t, err)
+		_, err = repos.LoginAttempts.RegisterFailure(ctx, models.LoginAttemptScopeIP, "alice", now, now.Add(-time.Hour))
+		require.NoError(t, err)
+
+		entry := models.NewAuditEntry(admin.ID, models.AuditUserUnlocked, models.AuditTargetUser, admin.ID, nil)
+		require.NoError(t, repos.Admin.UnlockLogin(ctx, "alice", entry))
+
+		_, err = repos.LoginAttempts.Get(ctx, models.LoginAttemptScopeLogin, "alice")
+		require.ErrorIs(t, err, errs.ErrLoginAttemptsNotFound)
+		_, err = repos.LoginAttempts.Get(ctx, models.LoginAttemptScopeIP, "alice")
+		require.NoError(t, err)
+	})
+}
+
+func createUser(t *testing.T, repos *services.Repositories, login string) *models.User {
+	t.Helper()
+	u := models.NewUser(login, "hash")
+	require.NoError(t, repos.Users.Create(context.Background(), u))
+	return u
+}
+
+// createUserWithPoints credits points through a processed order, the way
+// points get onto a balance in production.
+func createUserWithPoints(t *testing.T, repos *services.Repositories, login string, points models.Points) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	u := createUser(t, repos, login)
+	order := models.NewOrder("12345678903", u.ID)
+	require.NoError(t, repos.Orders.Create(ctx, order))
+	require.NoError(t, repos.Orders.UpdateStatusAndAccural(ctx, order.Number, models.StatusProcessed, &points))
+	return u
+}
@@ -2,22 +2,42 @@ package workers
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/Soliard/gophermart/internal/errs"
 	"github.com/Soliard/gophermart/internal/logger"
 	"github.com/Soliard/gophermart/internal/services"
 )
 
+// accrualStaleIntervals is how many poll intervals may pass without a
+// successful poll before CheckFreshness fails.
+const accrualStaleIntervals = 5
+
 type accrualUpdater struct {
-	accrual  services.AccrualServiceInterface
-	interval time.Duration
+	accrual     services.AccrualServiceInterface
+	interval    time.Duration
+	lastSuccess atomic.Int64
 }
 
 func NewAccrualUpdater(accrual services.AccrualServiceInterface, interval time.Duration) *accrualUpdater {
-	return &accrualUpdater{
+	u := &accrualUpdater{
 		accrual:  accrual,
 		interval: interval,
 	}
+	u.lastSuccess.Store(time.Now().UnixNano())
+	return u
+}
+
+// CheckFreshness fails when the last successful poll is too old. Before the
+// first poll the age is counted from the creation of the updater.
+func (u *accrualUpdater) CheckFreshness(ctx context.Context) error {
+	age := time.Since(time.Unix(0, u.lastSuccess.Load()))
+	if age > accrualStaleIntervals*u.interval {
+		return fmt.Errorf("%w: last success %s ago", errs.ErrAccrualPollStale, age.Round(time.Second))
+	}
+	return nil
 }
 
 func (u *accrualUpdater) Start(ctx context.Context) {
@@ -32,9 +52,12 @@ func (u *accrualUpdater) Start(ctx context.Context) {
 			return
 		case <-ticker.C:
 			err := u.accrual.UpdateOrders(ctx)
-			if err != nil {
+			if err != nil && ctx.Err() == nil {
 				log.Error("Failed to update accural orders", logger.F.Error(err))
 			}
+			if err == nil {
+				u.lastSuccess.Store(time.Now().UnixNano())
+			}
 		}
 	}
 }
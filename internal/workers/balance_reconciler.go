@@ -0,0 +1,44 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type balanceReconciler struct {
+	balance  services.BalanceServiceInterface
+	interval time.Duration
+}
+
+func NewBalanceReconciler(balance services.BalanceServiceInterface, interval time.Duration) *balanceReconciler {
+	return &balanceReconciler{
+		balance:  balance,
+		interval: interval,
+	}
+}
+
+func (r *balanceReconciler) Start(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Balance reconciler stopped")
+			return
+		case <-ticker.C:
+			mismatches, err := r.balance.Reconcile(ctx)
+			if err != nil {
+				log.Error("Failed to reconcile balances", logger.F.Error(err))
+				continue
+			}
+			if len(mismatches) > 0 {
+				log.Warn("Balance reconciliation found mismatches", logger.F.Int("count", len(mismatches)))
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type idempotencyCleaner struct {
+	idempotency services.IdempotencyServiceInterface
+	interval    time.Duration
+}
+
+func NewIdempotencyCleaner(idempotency services.IdempotencyServiceInterface, interval time.Duration) *idempotencyCleaner {
+	return &idempotencyCleaner{
+		idempotency: idempotency,
+		interval:    interval,
+	}
+}
+
+func (c *idempotencyCleaner) Start(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Idempotency cleaner stopped")
+			return
+		case <-ticker.C:
+			deleted, err := c.idempotency.DeleteExpired(ctx)
+			if err != nil {
+				log.Error("Failed to delete expired idempotency keys", logger.F.Error(err))
+				continue
+			}
+			log.Debug("Expired idempotency keys deleted", logger.F.Int("count", int(deleted)))
+		}
+	}
+}
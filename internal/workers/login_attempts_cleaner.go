@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soliard/gophermart/internal/logger"
+	"github.com/Soliard/gophermart/internal/services"
+)
+
+type loginAttemptsCleaner struct {
+	guard    services.LoginGuardInterface
+	interval time.Duration
+}
+
+func NewLoginAttemptsCleaner(guard services.LoginGuardInterface, interval time.Duration) *loginAttemptsCleaner {
+	return &loginAttemptsCleaner{
+		guard:    guard,
+		interval: interval,
+	}
+}
+
+func (c *loginAttemptsCleaner) Start(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Login attempts cleaner stopped")
+			return
+		case <-ticker.C:
+			deleted, err := c.guard.DeleteStale(ctx)
+			if err != nil {
+				log.Error("Failed to delete stale login attempts", logger.F.Error(err))
+				continue
+			}
+			log.Debug("Stale login attempts deleted", logger.F.Int("count", int(deleted)))
+		}
+	}
+}